@@ -0,0 +1,112 @@
+package audiospectrum
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// analysis is the decoded audio and spectrum of one render in a batch, kept
+// so later renders with matching options can skip decoding or the FFT
+type analysis struct {
+	audioKey     string
+	spectrumKey  string
+	audioData    []float64
+	spectrumData [][]float64
+	peaks        [][]float64
+}
+
+// GenerateBatch renders one video per config from the same input file,
+// reusing work between renders: the audio is decoded once per distinct set
+// of audio options (StartTime, Duration, Segments, SampleRate, SampleFormat,
+// SurroundDownmix), and the spectrum is computed once per distinct set of
+// analysis options on top of that (FPS, BarCount and the other Analysis
+// options). InputFile is replaced by input in each render; configs are not
+// modified. The returned slice holds the error of each config, nil for
+// renders that succeeded.
+func GenerateBatch(input string, configs []*Config) []error {
+	errs := make([]error, len(configs))
+	var done []*analysis
+	
+	for i, config := range configs {
+		c := *config
+		c.InputFile = input
+		
+		if err := checkDependencies(c.FFmpegPath, c.FFprobePath); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := checkConfig(&c); err != nil {
+			errs[i] = err
+			continue
+		}
+		
+		fmt.Printf("\nBatch render %d/%d: %s\n", i+1, len(configs), c.OutputFile)
+		visualizer := NewVisualizer(newVisualizerConfig(&c))
+		visualizer.prior = findAnalysis(done, visualizer)
+		
+		if _, err := renderVideo(&c, visualizer); err != nil {
+			errs[i] = err
+		}
+		
+		// Keep the analysis for later renders unless an earlier one matches it
+		if a := visualizer.analysis(); a != nil && findAnalysis(done, visualizer) == nil {
+			done = append(done, a)
+		}
+	}
+	
+	return errs
+}
+
+// findAnalysis returns the kept analysis with the same spectrum as v, or else
+// one with the same decoded audio, or nil
+func findAnalysis(done []*analysis, v *Visualizer) *analysis {
+	audioKey, spectrumKey := v.audioKey(), v.spectrumKey()
+	
+	var match *analysis
+	for _, a := range done {
+		if a.spectrumKey == spectrumKey {
+			return a
+		}
+		if match == nil && a.audioKey == audioKey {
+			match = a
+		}
+	}
+	return match
+}
+
+// analysis returns the audio and spectrum this visualizer computed, or nil
+// when it didn't get that far (such as a cache hit, which only probes)
+func (v *Visualizer) analysis() *analysis {
+	if v.audioData == nil || v.spectrumData == nil {
+		return nil
+	}
+	return &analysis{
+		audioKey:     v.audioKey(),
+		spectrumKey:  v.spectrumKey(),
+		audioData:    v.audioData,
+		spectrumData: v.spectrumData,
+		peaks:        v.peaks,
+	}
+}
+
+// audioKey identifies the options that decide the decoded audio samples
+func (v *Visualizer) audioKey() string {
+	c := v.config
+	return batchKey(c.InputFile, c.StartTime, c.Duration, c.Segments, c.SampleRate, c.SampleFormat, c.SurroundDownmix)
+}
+
+// spectrumKey identifies the options that decide the spectrum and peaks
+// computed from the decoded audio
+func (v *Visualizer) spectrumKey() string {
+	c := v.config
+	return batchKey(v.audioKey(), c.FPS, c.BarCount, c.HopLength, c.AmplitudeScale, c.DBFloor,
+		c.LogScaleMultiplier, c.LogScaleDivisor, c.Smoothing, c.Sensitivity, c.NoiseGate,
+		c.BinAggregation, c.FreqScale, c.Weighting, c.FractionalBins, c.Normalize, c.AGC,
+		c.AGCWindow, c.PeakHold, c.PeakDecay)
+}
+
+// batchKey joins option values into a comparable string
+func batchKey(values ...any) string {
+	key, _ := json.Marshal(values)
+	return string(key)
+}
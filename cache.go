@@ -0,0 +1,166 @@
+package audiospectrum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fogleman/gg"
+)
+
+// frameCacheVersion is part of every cache key; bump it when a rendering
+// change would make previously cached frames look different
+const frameCacheVersion = 1
+
+// frameCacheMarker is written into a cache entry once all its frames exist
+const frameCacheMarker = "complete"
+
+// frameCacheKey derives the cache entry name for this render. It is the
+// SHA-256 of the cache version, the config as JSON with the fields that only
+// affect encoding or scheduling cleared (output file and format, codec, CRF,
+// preset, audio bitrate, hardware encoder, pixel format, profile, level, loop
+// count, overwrite handling, process type, workers, reorder window, frame
+// limit, tool paths and cache dir), and the SHA-256 of the input and of any
+// background, watermark, font or center image file, so changing any visual
+// field or file contents selects a new entry. The side files WriteManifest
+// and ExportData are Config options the renderer never sees, so they are not
+// part of the key either.
+func (v *Visualizer) frameCacheKey() (string, error) {
+	visual := *v.config
+	visual.InputFile = ""
+	visual.OutputFile = ""
+	visual.OutputFormat = ""
+	visual.ProcessType = ""
+	visual.Workers = 0
+	visual.ReorderWindow = 0
+	visual.FrameLimit = 0
+	visual.FFmpegPath = ""
+	visual.FFprobePath = ""
+	visual.LoopCount = 0
+	visual.VideoCodec = ""
+	visual.VideoCRF = 0
+	visual.VideoPreset = ""
+	visual.AudioBitrate = ""
+	visual.HWAccel = ""
+	visual.PixelFormat = ""
+	visual.Profile = ""
+	visual.Level = ""
+	visual.NoOverwrite = false
+	visual.AutoIncrement = false
+	visual.CacheDir = ""
+
+	configJSON, err := json.Marshal(visual)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "v%d\n%s\n", frameCacheVersion, configJSON)
+	for _, path := range []string{v.config.InputFile, v.config.BackgroundImage, v.config.WatermarkFile, v.config.TitleFont, v.config.CenterImage} {
+		if path == "" {
+			continue
+		}
+		fileHash, err := fileSHA256(path)
+		if err != nil {
+			return "", fmt.Errorf("hashing %s: %w", path, err)
+		}
+		fmt.Fprintf(h, "%s\n", fileHash)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// openFrameCache selects the cache entry for this render and reports whether
+// it already holds a complete set of frames
+func (v *Visualizer) openFrameCache() (bool, error) {
+	key, err := v.frameCacheKey()
+	if err != nil {
+		return false, fmt.Errorf("computing frame cache key: %w", err)
+	}
+
+	v.cacheEntry = filepath.Join(v.config.CacheDir, key)
+	_, err = os.Stat(filepath.Join(v.cacheEntry, frameCacheMarker))
+	return err == nil, nil
+}
+
+// frameDir returns the directory frame images are written to: the cache entry
+// when caching, otherwise a temporary directory that cleanup removes
+func (v *Visualizer) frameDir() (string, func(), error) {
+	if v.cacheEntry != "" {
+		// Drop the marker so an interrupted re-render isn't mistaken for complete
+		os.Remove(filepath.Join(v.cacheEntry, frameCacheMarker))
+		if err := os.MkdirAll(v.cacheEntry, 0755); err != nil {
+			return "", nil, fmt.Errorf("creating frame cache dir: %w", err)
+		}
+		if existing, _ := filepath.Glob(filepath.Join(v.cacheEntry, "frame_*."+v.frameExt())); len(existing) > 0 {
+			fmt.Printf("Resuming with %d frames already in %s\n", len(existing), v.cacheEntry)
+		}
+		return v.cacheEntry, func() {}, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "spectrum_frames_*")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	return tempDir, func() { os.RemoveAll(tempDir) }, nil
+}
+
+// saveFrame renders a frame to filename. In a cache entry, frames left by an
+// interrupted run are kept, and new frames are written under a temporary name
+// and renamed so a crash never leaves a truncated frame to be resumed from.
+func (v *Visualizer) saveFrame(filename string, frameIdx int) error {
+	if v.cacheEntry == "" {
+		return v.writeFrameImage(filename, frameIdx)
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+
+	partial := filename + ".part"
+	if err := v.writeFrameImage(partial, frameIdx); err != nil {
+		return err
+	}
+	return os.Rename(partial, filename)
+}
+
+// writeFrameImage renders a frame and encodes it to filename as FrameFormat
+func (v *Visualizer) writeFrameImage(filename string, frameIdx int) error {
+	dc := v.generateFrame(frameIdx)
+	if v.frameExt() == "jpg" {
+		quality := v.config.FrameQuality
+		if quality <= 0 {
+			quality = 90
+		}
+		return gg.SaveJPG(filename, dc.Image(), quality)
+	}
+	return dc.SavePNG(filename)
+}
+
+// frameExt returns the file extension of frame images, without the dot
+func (v *Visualizer) frameExt() string {
+	if v.config.FrameFormat == FrameFormatJPEG {
+		return "jpg"
+	}
+	return "png"
+}
+
+// framePath returns the name of frame frameIdx's image in dir
+func (v *Visualizer) framePath(dir string, frameIdx int) string {
+	return filepath.Join(dir, fmt.Sprintf("frame_%06d.%s", frameIdx, v.frameExt()))
+}
+
+// markFramesComplete records that the cache entry holds every frame
+func (v *Visualizer) markFramesComplete() error {
+	if v.cacheEntry == "" {
+		return nil
+	}
+	marker := filepath.Join(v.cacheEntry, frameCacheMarker)
+	if err := os.WriteFile(marker, []byte(fmt.Sprintf("%d\n", v.totalFrames)), 0644); err != nil {
+		return fmt.Errorf("writing frame cache marker: %w", err)
+	}
+	return nil
+}
@@ -1,71 +1,307 @@
 package audiospectrum
 
 import (
+	"image"
+	"image/color"
+	"image/draw"
 	"math"
+	"slices"
+	"sort"
+	"strconv"
 
 	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
 )
 
+// glowSchemeColor is the GlowColor that makes each part glow in its own color
+const glowSchemeColor = "scheme"
+
+// glow returns the color to glow part index with at this magnitude, or nil
+// when the magnitude is at or below GlowThreshold or the glow is disabled
+func (v *Visualizer) glow(f *frameState, index int, magnitude float64) color.Color {
+	if v.config.GlowOpacity <= 0 || magnitude <= v.config.GlowThreshold {
+		return nil
+	}
+	
+	base := v.glowColor
+	if base == nil {
+		base = v.getBarColor(index, magnitude, f.hueShift)
+	}
+	r, g, b, _ := base.RGBA()
+	return color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(v.config.GlowOpacity * 255)}
+}
+
 // drawBars draws traditional bar spectrum
-func (v *Visualizer) drawBars(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawBars(dc *gg.Context, f *frameState) {
+	magnitudes, peaks := f.magnitudes, f.peaks
 	for i, magnitude := range magnitudes {
 		// Calculate bar height
-		baseHeight := 5.0
-		var barHeight float64
-		var displayMagnitude float64
-		
-		if magnitude < 0.05 { // Increase threshold for silence
-			barHeight = baseHeight + magnitude*float64(v.config.Height)*0.2 // Show very small bars for low values
-			displayMagnitude = magnitude * 2
-		} else {
-			barHeight = baseHeight + magnitude*float64(v.config.Height)*0.7
-			displayMagnitude = magnitude
-		}
+		barHeight := v.barHeight(magnitude)
 		
 		// Get color
-		color := v.getColor(displayMagnitude)
-		dc.SetColor(color)
+		color := v.getBarColor(i, magnitude, f.hueShift)
+		y := v.barBaseline() - barHeight
 		
-		// Draw bar
-		x := float64(v.barPositions[i])
-		barWidth := float64(v.barWidth) * 0.8
-		y := float64(v.config.Height) - barHeight
+		// Draw bar, twice when symmetric
+		xs, barWidth := v.barSlots(i)
+		for _, x := range xs {
+			if v.config.Reflection {
+				v.drawReflection(dc, color, x, barWidth, barHeight)
+			}
+			
+			dc.SetColor(color)
+			if v.config.SegmentedBars {
+				v.drawSegments(dc, f, i, x, barWidth, barHeight)
+			} else {
+				if v.config.BarGradient {
+					dc.SetFillStyle(v.barGradient(f, i, x, y, barHeight, magnitude))
+				}
+				ox, oy, ow, oh := v.orientRect(x, y, barWidth, barHeight)
+				v.drawBarShape(dc, ox, oy, ow, oh, 0)
+				dc.Fill()
+				
+				if v.config.BarBevel {
+					v.drawBevel(dc, color, x, y, barWidth, barHeight)
+				}
+				
+				// Add glow effect for louder parts
+				if glow := v.glow(f, i, magnitude); glow != nil {
+					dc.SetColor(glow)
+					ox, oy, ow, oh = v.orientRect(x-2, y-2, barWidth+4, barHeight+4)
+					v.drawBarShape(dc, ox, oy, ow, oh, 2)
+					dc.Fill()
+				}
+			}
+			
+			// Draw peak-hold cap
+			if i < len(peaks) {
+				peakY := v.barBaseline() - v.barHeight(peaks[i])
+				dc.SetColor(v.getBarColor(i, peaks[i], f.hueShift))
+				dc.DrawRectangle(v.orientRect(x, peakY-peakCapHeight, barWidth, peakCapHeight))
+				dc.Fill()
+			}
+		}
+	}
+}
+
+// barGradient returns the fill for a BarGradient bar standing at x with its
+// top at y: the band's silent color at the baseline rising to its color at
+// the bar's magnitude at the top, so taller bars reach further up the scheme
+func (v *Visualizer) barGradient(f *frameState, index int, x, y, barHeight, magnitude float64) gg.Gradient {
+	x0, y0, _, _ := v.orientRect(x, y+barHeight, 0, 0)
+	x1, y1, _, _ := v.orientRect(x, y, 0, 0)
+	gradient := gg.NewLinearGradient(x0, y0, x1, y1)
+	gradient.AddColorStop(0, v.getBarColor(index, 0, f.hueShift))
+	gradient.AddColorStop(1, v.getBarColor(index, magnitude, f.hueShift))
+	return gradient
+}
+
+// barSlot returns the left edge and width of bar i, leaving BarGap of each
+// bar's slot as space split evenly on both sides
+func (v *Visualizer) barSlot(i int) (float64, float64) {
+	gap := v.barWidth * v.config.BarGap
+	return v.barPositions[i] + gap/2, v.barWidth - gap
+}
+
+// barSlots returns the left edges and width of the bars drawn for band i in
+// drawBars: its barSlot, or with Symmetric that layout squeezed into each half
+// of the frame and mirrored, so band 0 sits at the center and the highest
+// bands reach both edges
+func (v *Visualizer) barSlots(i int) ([]float64, float64) {
+	x, width := v.barSlot(i)
+	if !v.config.Symmetric {
+		return []float64{x}, width
+	}
+	
+	right := float64(v.config.Width)/2 + x/2
+	left := float64(v.config.Width) - right - width/2
+	return []float64{left, right}, width / 2
+}
+
+// orientRect maps a rectangle of the bars visualization from its bottom-up
+// layout, with bars along the width growing up from the bottom edge, to the
+// configured Orientation. The sideways orientations lay the bars out down the
+// height and stretch their length to the width.
+func (v *Visualizer) orientRect(x, y, w, h float64) (float64, float64, float64, float64) {
+	width, height := float64(v.config.Width), float64(v.config.Height)
+	switch v.config.Orientation {
+	case "top-down":
+		return x, height - y - h, w, h
+	case "left-right", "right-left":
+		along, across := height/width, width/height
+		ox, oy, ow, oh := (height-y-h)*across, x*along, h*across, w*along
+		if v.config.Orientation == "right-left" {
+			ox = width - ox - ow
+		}
+		return ox, oy, ow, oh
+	}
+	return x, y, w, h
+}
+
+// drawBarShape adds a bar outline to the path, with corners rounded by
+// BarCornerRadius plus grow (for outlines around the bar) when it's set. The
+// radius is clamped to half the bar's width and height so short bars stay pills.
+func (v *Visualizer) drawBarShape(dc *gg.Context, x, y, width, height, grow float64) {
+	if v.config.BarCornerRadius <= 0 {
+		dc.DrawRectangle(x, y, width, height)
+		return
+	}
+	radius := math.Min(v.config.BarCornerRadius+grow, math.Min(width, height)/2)
+	dc.DrawRoundedRectangle(x, y, width, height, radius)
+}
+
+// peakCapHeight is the thickness of the peak-hold caps in pixels
+const peakCapHeight = 3.0
+
+// barHeight maps a magnitude to the height of a bar in drawBars, standing on
+// a MinBarHeight baseline so silence draws as an even strip
+func (v *Visualizer) barHeight(magnitude float64) float64 {
+	return v.config.MinBarHeight + v.curveHeight(magnitude)*float64(v.config.Height)*0.7
+}
+
+// curveHeight applies HeightCurve to a 0-1 magnitude, giving the fraction of
+// its full length a bar-style element is drawn at
+func (v *Visualizer) curveHeight(magnitude float64) float64 {
+	switch v.config.HeightCurve {
+	case "sqrt":
+		return math.Sqrt(math.Max(magnitude, 0))
+	case "square":
+		return magnitude * magnitude
+	}
+	return magnitude
+}
+
+// reflectionSpace is the fraction of the height below the bars kept for
+// their reflection
+const reflectionSpace = 0.2
+
+// barBaseline returns the y the bars visualization grows up from in its
+// bottom-up layout: the bottom edge, or above the reflection when enabled
+func (v *Visualizer) barBaseline() float64 {
+	height := float64(v.config.Height)
+	if v.config.Reflection {
+		return height * (1 - reflectionSpace)
+	}
+	return height
+}
+
+// drawReflection draws a bar flipped below the baseline, fading from
+// ReflectionOpacity at the baseline to nothing, cut off at the frame edge
+func (v *Visualizer) drawReflection(dc *gg.Context, fill color.Color, x, width, barHeight float64) {
+	baseline := v.barBaseline()
+	length := math.Min(barHeight, float64(v.config.Height)-baseline)
+	if length <= 0 {
+		return
+	}
+	
+	// The gradient runs in frame coordinates, so map its ends like the bar
+	x0, y0, _, _ := v.orientRect(x, baseline, 0, 0)
+	x1, y1, _, _ := v.orientRect(x, baseline+length, 0, 0)
+	r, g, b, _ := fill.RGBA()
+	fade := gg.NewLinearGradient(x0, y0, x1, y1)
+	fade.AddColorStop(0, color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(v.config.ReflectionOpacity * 255)})
+	fade.AddColorStop(1, color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0})
+	
+	ox, oy, ow, oh := v.orientRect(x, baseline, width, length)
+	v.drawBarShape(dc, ox, oy, ow, oh, 0)
+	dc.SetFillStyle(fade)
+	dc.Fill()
+}
+
+// drawSegments draws a bar as a stack of LED-style segments spanning the
+// full bar range, lighting those below the bar height and dimming the rest
+func (v *Visualizer) drawSegments(dc *gg.Context, f *frameState, index int, x, barWidth, barHeight float64) {
+	count := v.config.SegmentCount
+	maxHeight := v.barHeight(1)
+	segmentHeight := maxHeight / float64(count)
+	gap := math.Max(1, segmentHeight*0.2)
+	
+	for j := 0; j < count; j++ {
+		level := float64(j+1) / float64(count)
+		segmentColor := v.getBarColor(index, level, f.hueShift)
+		if float64(j)*segmentHeight+segmentHeight/2 > barHeight {
+			segmentColor = shadeColor(segmentColor, -0.85)
+		}
 		
-		dc.DrawRectangle(x, y, barWidth, barHeight)
+		y := v.barBaseline() - float64(j+1)*segmentHeight
+		dc.SetColor(segmentColor)
+		dc.DrawRectangle(v.orientRect(x, y+gap/2, barWidth, segmentHeight-gap))
 		dc.Fill()
-		
-		// Add glow effect for louder parts
-		if magnitude > 0.5 {
-			dc.SetRGBA(1, 1, 1, 0.3)
-			dc.DrawRectangle(x-2, y-2, barWidth+4, barHeight+4)
-			dc.Fill()
-		}
 	}
 }
 
+// drawBevel draws a lighter top edge and darker side edges on a bar, given
+// in its bottom-up layout
+func (v *Visualizer) drawBevel(dc *gg.Context, fill color.Color, x, y, w, h float64) {
+	edge := math.Max(1, math.Min(w, h)*0.1)
+	
+	// Darker sides
+	dc.SetColor(shadeColor(fill, -0.35))
+	dc.DrawRectangle(v.orientRect(x, y, edge, h))
+	dc.DrawRectangle(v.orientRect(x+w-edge, y, edge, h))
+	dc.Fill()
+	
+	// Lighter top edge
+	dc.SetColor(shadeColor(fill, 0.45))
+	dc.DrawRectangle(v.orientRect(x, y, w, edge))
+	dc.Fill()
+}
+
+// circleRadii returns the inner and outer radius of the circular
+// visualizations; the inner one is CenterRadius when set
+func (v *Visualizer) circleRadii() (float64, float64) {
+	inner := 80.0
+	if v.config.CenterRadius > 0 {
+		inner = v.config.CenterRadius
+	}
+	return inner, math.Min(float64(v.config.Width), float64(v.config.Height))/2 - 50
+}
+
+// drawCenterImage fills the hole of the circular visualizations with the
+// center image clipped to a circle, grown by CenterPulse times the bass level
+func (v *Visualizer) drawCenterImage(dc *gg.Context, magnitudes []float64) {
+	inner, _ := v.circleRadii()
+	grow := 1 + v.config.CenterPulse*bassLevel(magnitudes)
+	scale := grow / (1 + v.config.CenterPulse)
+	cx, cy := float64(v.centerX), float64(v.centerY)
+	
+	dc.Push()
+	dc.DrawCircle(cx, cy, inner*grow)
+	dc.Clip()
+	dc.ScaleAbout(scale, scale, cx, cy)
+	dc.DrawImageAnchored(v.centerImage, v.centerX, v.centerY, 0.5, 0.5)
+	// Pop keeps the clip mask, so clear it explicitly
+	dc.ResetClip()
+	dc.Pop()
+}
+
+// bassLevel is the mean magnitude of the lowest quarter of the bands
+func bassLevel(magnitudes []float64) float64 {
+	count := max(1, len(magnitudes)/4)
+	if len(magnitudes) < count {
+		return 0
+	}
+	var sum float64
+	for _, m := range magnitudes[:count] {
+		sum += m
+	}
+	return sum / float64(count)
+}
+
 // drawCircular draws circular spectrum with bars radiating outward
-func (v *Visualizer) drawCircular(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawCircular(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
 	angleStep := 2 * math.Pi / float64(v.config.BarCount)
-	minRadius := 80.0
-	maxRadius := math.Min(float64(v.config.Width), float64(v.config.Height))/2 - 50
+	minRadius, maxRadius := v.circleRadii()
 	
 	for i, magnitude := range magnitudes {
-		angle := float64(i) * angleStep
+		angle := float64(i)*angleStep + f.rotation
 		
-		// Calculate radius
-		var radius float64
-		var displayMagnitude float64
-		
-		if magnitude < 0.01 {
-			radius = minRadius + 5
-			displayMagnitude = 0.1
-		} else {
-			radius = minRadius + magnitude*(maxRadius-minRadius)
-			displayMagnitude = magnitude
-		}
+		// Calculate radius, keeping a short stub in silence
+		radius := minRadius + math.Max(5, v.curveHeight(magnitude)*(maxRadius-minRadius))
 		
 		// Get color
-		color := v.getColor(displayMagnitude)
+		color := v.getBarColor(i, magnitude, f.hueShift)
 		dc.SetColor(color)
 		
 		// Calculate line endpoints
@@ -80,9 +316,9 @@ func (v *Visualizer) drawCircular(dc *gg.Context, magnitudes []float64) {
 		dc.Stroke()
 		
 		// Add glow for loud parts
-		if magnitude > 0.5 {
+		if glow := v.glow(f, i, magnitude); glow != nil {
 			dc.SetLineWidth(12)
-			dc.SetRGBA(1, 1, 1, 0.3)
+			dc.SetColor(glow)
 			dc.DrawLine(x1, y1, x2, y2)
 			dc.Stroke()
 		}
@@ -90,16 +326,22 @@ func (v *Visualizer) drawCircular(dc *gg.Context, magnitudes []float64) {
 }
 
 // drawWave draws wave-form spectrum
-func (v *Visualizer) drawWave(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawWave(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
+	if len(magnitudes) == 0 {
+		return
+	}
+	
+	levels, steps := v.interpolate(magnitudes)
 	yCenter := float64(v.config.Height) / 2
-	xStep := float64(v.config.Width) / float64(len(magnitudes))
+	xStep := float64(v.config.Width) / float64(len(levels))
 	
-	for i, magnitude := range magnitudes {
+	for i, magnitude := range levels {
 		x := float64(i) * xStep
 		waveHeight := 20 + magnitude*150
 		
-		// Get color
-		color := v.getColor(magnitude)
+		// Get color of the band this point belongs to
+		color := v.getBarColor(bandOf(i, steps), magnitude, f.hueShift)
 		dc.SetColor(color)
 		
 		// Draw vertical line from center
@@ -110,27 +352,19 @@ func (v *Visualizer) drawWave(dc *gg.Context, magnitudes []float64) {
 }
 
 // drawRadial draws radial burst spectrum
-func (v *Visualizer) drawRadial(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawRadial(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
 	angleStep := 2 * math.Pi / float64(v.config.BarCount)
 	baseRadius := 50.0
 	
 	for i, magnitude := range magnitudes {
-		angle := float64(i) * angleStep
+		angle := float64(i)*angleStep + f.rotation
 		
 		// Create wedge shape
-		var length float64
-		var displayMagnitude float64
-		
-		if magnitude < 0.01 {
-			length = 10
-			displayMagnitude = 0.1
-		} else {
-			length = 10 + magnitude*300
-			displayMagnitude = magnitude
-		}
+		length := 10 + v.curveHeight(magnitude)*300
 		
 		// Get color
-		color := v.getColor(displayMagnitude)
+		color := v.getBarColor(i, magnitude, f.hueShift)
 		dc.SetColor(color)
 		
 		// Calculate wedge points
@@ -153,57 +387,189 @@ func (v *Visualizer) drawRadial(dc *gg.Context, magnitudes []float64) {
 			float64(v.centerY)+(baseRadius+length)*math.Sin(angle-angleWidth/2),
 		)
 		dc.ClosePath()
-		dc.Fill()
 		
-		// Add glow for loud parts
-		if magnitude > 0.5 {
-			dc.SetRGBA(1, 1, 1, 0.3)
+		// Add glow for loud parts, keeping the wedge path to outline it
+		if glow := v.glow(f, i, magnitude); glow != nil {
+			dc.FillPreserve()
+			dc.SetColor(glow)
 			dc.SetLineWidth(3)
 			dc.Stroke()
+		} else {
+			dc.Fill()
 		}
 	}
 }
 
 // drawLine draws connected line spectrum
-func (v *Visualizer) drawLine(dc *gg.Context, magnitudes []float64) {
-	xStep := float64(v.config.Width) / float64(len(magnitudes)-1)
+func (v *Visualizer) drawLine(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
+	// A line needs at least two points to span the width
+	if len(magnitudes) < 2 {
+		return
+	}
+	
+	levels, steps := v.interpolate(magnitudes)
+	points := v.linePoints(levels)
 	
 	// Start path
-	dc.MoveTo(0, float64(v.config.Height)-50-magnitudes[0]*float64(v.config.Height-100))
+	dc.MoveTo(points[0].X, points[0].Y)
 	
 	// Draw connected lines
-	for i := 1; i < len(magnitudes); i++ {
-		x := float64(i) * xStep
-		y := float64(v.config.Height) - 50 - magnitudes[i]*float64(v.config.Height-100)
+	for i := 1; i < len(levels); i++ {
+		x, y := points[i].X, points[i].Y
 		
 		// Get color for this segment
-		color := v.getColor(magnitudes[i])
+		color := v.getBarColor(bandOf(i, steps), levels[i], f.hueShift)
 		dc.SetColor(color)
 		dc.SetLineWidth(5)
 		
-		dc.LineTo(x, y)
+		if v.config.SmoothLine {
+			c1, c2 := splineControls(points, i)
+			dc.CubicTo(c1.X, c1.Y, c2.X, c2.Y, x, y)
+		} else {
+			dc.LineTo(x, y)
+		}
+		
+		// Add glow for loud parts, keeping the segment path to widen it
+		if glow := v.glow(f, bandOf(i, steps), levels[i]); glow != nil {
+			dc.StrokePreserve()
+			dc.SetColor(glow)
+			dc.SetLineWidth(8)
+		}
 		dc.Stroke()
 		dc.MoveTo(x, y)
+	}
+}
+
+// linePoints returns the points of the line spectrum, spread across the
+// width and rising from 50px above the bottom edge
+func (v *Visualizer) linePoints(magnitudes []float64) []gg.Point {
+	xStep := float64(v.config.Width) / float64(len(magnitudes)-1)
+	
+	points := make([]gg.Point, len(magnitudes))
+	for i, magnitude := range magnitudes {
+		points[i] = gg.Point{
+			X: float64(i) * xStep,
+			Y: float64(v.config.Height) - 50 - magnitude*float64(v.config.Height-100),
+		}
+	}
+	return points
+}
+
+// drawArea fills the area between the line spectrum and its baseline, like
+// a waveform envelope in an audio editor. The fill blends the bar colors
+// across the width, or with BarGradient rises from the scheme's silent color
+// at the baseline to the color of the loudest band at the top of the curve.
+func (v *Visualizer) drawArea(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
+	// An area needs at least two points to span the width
+	if len(magnitudes) < 2 {
+		return
+	}
+	
+	levels, _ := v.interpolate(magnitudes)
+	points := v.linePoints(levels)
+	baseline := float64(v.config.Height) - 50
+	
+	dc.MoveTo(points[0].X, baseline)
+	dc.LineTo(points[0].X, points[0].Y)
+	for i := 1; i < len(points); i++ {
+		if v.config.SmoothLine {
+			c1, c2 := splineControls(points, i)
+			dc.CubicTo(c1.X, c1.Y, c2.X, c2.Y, points[i].X, points[i].Y)
+		} else {
+			dc.LineTo(points[i].X, points[i].Y)
+		}
+	}
+	dc.LineTo(points[len(points)-1].X, baseline)
+	dc.ClosePath()
+	
+	var fill gg.Gradient
+	if v.config.BarGradient {
+		loudest := slices.Max(levels)
+		top := baseline - loudest*float64(v.config.Height-100)
+		fill = gg.NewLinearGradient(0, baseline, 0, top)
+		fill.AddColorStop(0, v.getColor(0, f.hueShift))
+		fill.AddColorStop(1, v.getColor(loudest, f.hueShift))
+	} else {
+		fill = gg.NewLinearGradient(0, 0, float64(v.config.Width), 0)
+		for i, magnitude := range magnitudes {
+			fill.AddColorStop(float64(i)/float64(len(magnitudes)-1), v.getBarColor(i, magnitude, f.hueShift))
+		}
+	}
+	dc.SetFillStyle(fill)
+	dc.Fill()
+}
+
+// interpolationSteps is the number of points the line, wave and area types
+// draw per band when Interpolation is set
+const interpolationSteps = 4
+
+// interpolate upsamples the band levels for the line, wave and area types
+// with the configured Interpolation, returning the denser levels and the
+// number of points drawn per band
+func (v *Visualizer) interpolate(magnitudes []float64) ([]float64, int) {
+	mode := v.config.Interpolation
+	if len(magnitudes) < 2 || mode == "" || mode == "none" {
+		return magnitudes, 1
+	}
+	
+	last := len(magnitudes) - 1
+	levels := make([]float64, last*interpolationSteps+1)
+	for i := range levels {
+		j := min(i/interpolationSteps, last-1)
+		t := float64(i-j*interpolationSteps) / interpolationSteps
+		p1, p2 := magnitudes[j], magnitudes[j+1]
 		
-		// Add glow for loud parts
-		if magnitudes[i] > 0.5 {
-			dc.SetRGBA(1, 1, 1, 0.3)
-			dc.SetLineWidth(8)
-			dc.Stroke()
-			dc.MoveTo(x, y)
+		if mode == "cubic" {
+			// Catmull-Rom through the neighboring bands, which can overshoot
+			p0, p3 := magnitudes[max(j-1, 0)], magnitudes[min(j+2, last)]
+			level := p1 + 0.5*t*(p2-p0+t*(2*p0-5*p1+4*p2-p3+t*(3*(p1-p2)+p3-p0)))
+			levels[i] = math.Max(0, math.Min(1, level))
+		} else {
+			levels[i] = p1 + (p2-p1)*t
 		}
 	}
+	return levels, interpolationSteps
+}
+
+// bandOf returns the band an interpolated point belongs to, for its color
+func bandOf(i, steps int) int {
+	return (i + steps/2) / steps
+}
+
+// splineControls returns the cubic bezier control points for the segment
+// ending at points[i], using Catmull-Rom tangents from the neighbors
+func splineControls(points []gg.Point, i int) (gg.Point, gg.Point) {
+	p0 := points[max(i-2, 0)]
+	p1 := points[i-1]
+	p2 := points[i]
+	p3 := points[min(i+1, len(points)-1)]
+	
+	c1 := gg.Point{X: p1.X + (p2.X-p0.X)/6, Y: p1.Y + (p2.Y-p0.Y)/6}
+	c2 := gg.Point{X: p2.X - (p3.X-p1.X)/6, Y: p2.Y - (p3.Y-p1.Y)/6}
+	return c1, c2
 }
 
 // drawDots draws dots/particles spectrum
-func (v *Visualizer) drawDots(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawDots(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
 	xStep := float64(v.config.Width) / float64(len(magnitudes))
 	
+	maxDots := 10
+	if v.config.DotsMaxCount > 0 {
+		maxDots = v.config.DotsMaxCount
+	}
+	dotSize := 1.0
+	if v.config.DotSize > 0 {
+		dotSize = v.config.DotSize
+	}
+	
 	for i, magnitude := range magnitudes {
 		x := float64(i)*xStep + xStep/2
 		
 		// Create multiple dots at different heights
-		numDots := int(1 + magnitude*10)
+		numDots := int(1 + magnitude*float64(maxDots))
 		
 		for j := 0; j < numDots; j++ {
 			y := float64(v.config.Height) - 20 - float64(j*30) - magnitude*300
@@ -212,17 +578,17 @@ func (v *Visualizer) drawDots(dc *gg.Context, magnitudes []float64) {
 			}
 			
 			// Get color
-			color := v.getColor(magnitude * (1 - float64(j)/10))
+			color := v.getBarColor(i, magnitude*(1-float64(j)/float64(maxDots)), f.hueShift)
 			dc.SetColor(color)
 			
 			// Draw dot
-			radius := 3 + magnitude*5
+			radius := (3 + magnitude*5) * dotSize
 			dc.DrawCircle(x, y, radius)
 			dc.Fill()
 			
 			// Add glow
-			if magnitude > 0.5 {
-				dc.SetRGBA(1, 1, 1, 0.3)
+			if glow := v.glow(f, i, magnitude); glow != nil {
+				dc.SetColor(glow)
 				dc.DrawCircle(x, y, radius+3)
 				dc.Stroke()
 			}
@@ -231,30 +597,47 @@ func (v *Visualizer) drawDots(dc *gg.Context, magnitudes []float64) {
 }
 
 // drawMirror draws mirror spectrum - bars from center going up and down
-func (v *Visualizer) drawMirror(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawMirror(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
+	for i, magnitude := range magnitudes {
+		x, barWidth := v.barSlot(i)
+		v.drawMirrorBar(dc, f, i, magnitude, x, barWidth)
+	}
+}
+
+// drawButterfly draws the mirror bars squeezed into each half of the frame
+// and mirrored about the vertical center, so the lowest band sits at both
+// edges and the highest bands meet in the middle like a pair of wings
+func (v *Visualizer) drawButterfly(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
+	for i, magnitude := range magnitudes {
+		x, barWidth := v.barSlot(i)
+		left := x / 2
+		right := float64(v.config.Width) - left - barWidth/2
+		v.drawMirrorBar(dc, f, i, magnitude, left, barWidth/2)
+		v.drawMirrorBar(dc, f, i, magnitude, right, barWidth/2)
+	}
+}
+
+// drawMirrorBar draws band i as a bar at x going up and down from the
+// vertical center, with its glow outline and peak-hold caps
+func (v *Visualizer) drawMirrorBar(dc *gg.Context, f *frameState, i int, magnitude float64, x, barWidth float64) {
+	peaks := f.peaks
 	yCenter := float64(v.config.Height) / 2
 	
-	for i, magnitude := range magnitudes {
-		// Calculate bar height
-		var barHeight float64
-		var displayMagnitude float64
-		
-		if magnitude < 0.01 {
-			barHeight = 5
-			displayMagnitude = 0.1
-		} else {
-			barHeight = 5 + magnitude*float64(v.config.Height)*0.35
-			displayMagnitude = magnitude
-		}
-		
-		// Get color
-		color := v.getColor(displayMagnitude)
-		dc.SetColor(color)
-		
-		// Draw bars going up and down from center
-		x := float64(v.barPositions[i])
-		barWidth := float64(v.barWidth) * 0.8
-		
+	// Calculate bar height
+	barHeight := v.mirrorBarHeight(magnitude)
+	
+	// Get color
+	color := v.getBarColor(i, magnitude, f.hueShift)
+	dc.SetColor(color)
+	
+	// Draw bars going up and down from center
+	if v.config.BarCornerRadius > 0 {
+		// One rounded bar spanning both halves, so there's no pinch at the center
+		v.drawBarShape(dc, x, yCenter-barHeight, barWidth, barHeight*2, 0)
+		dc.Fill()
+	} else {
 		// Upper bar
 		dc.DrawRectangle(x, yCenter-barHeight, barWidth, barHeight)
 		dc.Fill()
@@ -262,19 +645,39 @@ func (v *Visualizer) drawMirror(dc *gg.Context, magnitudes []float64) {
 		// Lower bar
 		dc.DrawRectangle(x, yCenter, barWidth, barHeight)
 		dc.Fill()
-		
-		// Add glow for loud parts
-		if magnitude > 0.5 {
-			dc.SetRGBA(1, 1, 1, 0.3)
-			dc.DrawRectangle(x-2, yCenter-barHeight-2, barWidth+4, barHeight*2+4)
-			dc.Stroke()
-		}
 	}
+	
+	// Add glow outline for loud parts; the fills above consume their
+	// paths, so build the outline right before stroking it
+	if glow := v.glow(f, i, magnitude); glow != nil {
+		dc.SetColor(glow)
+		dc.SetLineWidth(4)
+		v.drawBarShape(dc, x-2, yCenter-barHeight-2, barWidth+4, barHeight*2+4, 2)
+		dc.Stroke()
+	}
+	
+	// Draw peak-hold caps above and below
+	if i < len(peaks) {
+		peakHeight := v.mirrorBarHeight(peaks[i])
+		dc.SetColor(v.getBarColor(i, peaks[i], f.hueShift))
+		dc.DrawRectangle(x, yCenter-peakHeight-peakCapHeight, barWidth, peakCapHeight)
+		dc.DrawRectangle(x, yCenter+peakHeight, barWidth, peakCapHeight)
+		dc.Fill()
+	}
+}
+
+// mirrorBarHeight maps a magnitude to the height of each half of a mirror bar
+func (v *Visualizer) mirrorBarHeight(magnitude float64) float64 {
+	return 5 + v.curveHeight(magnitude)*float64(v.config.Height)*0.35
 }
 
 // drawSpiral draws spiral spectrum
-func (v *Visualizer) drawSpiral(dc *gg.Context, magnitudes []float64) {
+func (v *Visualizer) drawSpiral(dc *gg.Context, f *frameState) {
+	magnitudes := f.magnitudes
 	turns := 2.0 // Number of spiral turns
+	if v.config.SpiralTurns > 0 {
+		turns = v.config.SpiralTurns
+	}
 	maxRadius := math.Min(float64(v.config.Width), float64(v.config.Height))/2 - 50
 	
 	for i := 0; i < len(magnitudes); i++ {
@@ -285,7 +688,7 @@ func (v *Visualizer) drawSpiral(dc *gg.Context, magnitudes []float64) {
 		angleEnd := (float64(i+1) / float64(len(magnitudes))) * 2 * math.Pi * turns
 		
 		// Get color
-		color := v.getColor(magnitude)
+		color := v.getBarColor(i, magnitude, f.hueShift)
 		dc.SetColor(color)
 		
 		// Create points along the spiral segment
@@ -295,8 +698,8 @@ func (v *Visualizer) drawSpiral(dc *gg.Context, magnitudes []float64) {
 			angle := angleStart + t*(angleEnd-angleStart)
 			radius := 50 + (angle/(2*math.Pi*turns))*maxRadius + magnitude*50
 			
-			x := float64(v.centerX) + radius*math.Cos(angle)
-			y := float64(v.centerY) + radius*math.Sin(angle)
+			x := float64(v.centerX) + radius*math.Cos(angle+f.rotation)
+			y := float64(v.centerY) + radius*math.Sin(angle+f.rotation)
 			
 			if j > 0 {
 				thickness := 2 + magnitude*8
@@ -308,4 +711,332 @@ func (v *Visualizer) drawSpiral(dc *gg.Context, magnitudes []float64) {
 			prevX, prevY = x, y
 		}
 	}
-}
\ No newline at end of file
+}
+
+// spectrogramColumnWidth is how far the spectrogram scrolls per frame in pixels
+const spectrogramColumnWidth = 4
+
+// drawSpectrogram draws a waterfall of recent frames scrolling right to left,
+// with the current frame as the rightmost column and low frequencies at the
+// bottom. Columns are read back from the precomputed spectrum data, so no
+// state is carried between frames.
+func (v *Visualizer) drawSpectrogram(dc *gg.Context, f *frameState) {
+	img, ok := dc.Image().(*image.RGBA)
+	if !ok {
+		return
+	}
+	
+	columns := (v.config.Width + spectrogramColumnWidth - 1) / spectrogramColumnWidth
+	rowHeight := float64(v.config.Height) / float64(v.config.BarCount)
+	
+	for c := 0; c < columns; c++ {
+		frame := f.specIdx - c
+		if frame < 0 {
+			break
+		}
+		if frame >= len(v.spectrumData) {
+			continue
+		}
+		
+		x1 := v.config.Width - c*spectrogramColumnWidth
+		x0 := x1 - spectrogramColumnWidth
+		for i, magnitude := range v.silenceGate(v.spectrumData[frame]) {
+			y0 := int(float64(v.config.Height) - float64(i+1)*rowHeight)
+			y1 := int(float64(v.config.Height) - float64(i)*rowHeight)
+			rect := image.Rect(x0, y0, x1, y1)
+			draw.Draw(img, rect, image.NewUniform(v.getBarColor(i, magnitude, f.hueShift)), image.Point{}, draw.Src)
+		}
+	}
+}
+
+// drawOscilloscope draws the raw time-domain samples as a connected trace
+// across the screen, colored by instantaneous amplitude (and in frequency
+// color mode by horizontal position, as if the bars were spread under it)
+func (v *Visualizer) drawOscilloscope(dc *gg.Context, f *frameState) {
+	samples := v.frameSamples(f.specIdx)
+	yCenter := float64(v.config.Height) / 2
+	amplitude := float64(v.config.Height) / 2 * 0.9
+	
+	if len(samples) < 2 {
+		dc.SetColor(v.getColor(0, f.hueShift))
+		dc.SetLineWidth(3)
+		dc.DrawLine(0, yCenter, float64(v.config.Width), yCenter)
+		dc.Stroke()
+		return
+	}
+	
+	// Draw at most one point every two pixels
+	step := 1
+	if maxPoints := v.config.Width / 2; len(samples) > maxPoints {
+		step = len(samples) / maxPoints
+	}
+	xScale := float64(v.config.Width) / float64(len(samples)-1)
+	
+	dc.SetLineWidth(3)
+	prevX, prevY := 0.0, yCenter-clampUnit(samples[0])*amplitude
+	for i := step; i < len(samples); i += step {
+		sample := clampUnit(samples[i])
+		x := float64(i) * xScale
+		y := yCenter - sample*amplitude
+		
+		dc.SetColor(v.getBarColor(v.config.BarCount*i/len(samples), math.Abs(sample), f.hueShift))
+		dc.DrawLine(prevX, prevY, x, y)
+		dc.Stroke()
+		
+		prevX, prevY = x, y
+	}
+}
+
+// circularWavePoints is the most points drawn around the circular waveform
+const circularWavePoints = 720
+
+// drawCircularWave draws the raw time-domain samples around a ring, with the
+// amplitude pushing the radius in and out, colored by instantaneous amplitude
+// (and in frequency color mode by angle, once around the wheel)
+func (v *Visualizer) drawCircularWave(dc *gg.Context, f *frameState) {
+	samples := v.frameSamples(f.specIdx)
+	minRadius, maxRadius := v.circleRadii()
+	baseRadius := (minRadius + maxRadius) / 2
+	amplitude := (maxRadius - minRadius) / 2
+	cx, cy := float64(v.centerX), float64(v.centerY)
+	
+	dc.SetLineWidth(3)
+	if len(samples) < 2 {
+		dc.SetColor(v.getColor(0, f.hueShift))
+		dc.DrawCircle(cx, cy, baseRadius)
+		dc.Stroke()
+		return
+	}
+	
+	step := 1
+	if len(samples) > circularWavePoints {
+		step = len(samples) / circularWavePoints
+	}
+	count := len(samples) / step
+	
+	// Fade the last tenth of the trace into the first sample so the ring
+	// closes without a jump
+	first := clampUnit(samples[0])
+	fadeStart := count - count/10
+	point := func(k int) (float64, float64, float64) {
+		sample := clampUnit(samples[k*step])
+		if k >= fadeStart {
+			t := float64(k-fadeStart+1) / float64(count-fadeStart+1)
+			sample += (first - sample) * t
+		}
+		angle := 2*math.Pi*float64(k)/float64(count) - math.Pi/2
+		radius := baseRadius + sample*amplitude
+		return cx + radius*math.Cos(angle), cy + radius*math.Sin(angle), sample
+	}
+	
+	prevX, prevY, _ := point(0)
+	for k := 1; k <= count; k++ {
+		x, y, sample := point(k % count)
+		dc.SetColor(v.getBarColor(v.config.BarCount*(k-1)/count, math.Abs(sample), f.hueShift))
+		dc.DrawLine(prevX, prevY, x, y)
+		dc.Stroke()
+		
+		prevX, prevY = x, y
+	}
+}
+
+// vuMeterSegments is the number of LEDs in the VU meter unless segmented
+// bars set their own SegmentCount
+const vuMeterSegments = 40
+
+// drawVUMeter draws the RMS level of the frame's samples as a horizontal row
+// of LEDs across the middle of the frame. Lit LEDs are colored by their
+// position on the meter, so the default rainbow scheme runs green, yellow,
+// red; unlit LEDs stay dimly visible.
+func (v *Visualizer) drawVUMeter(dc *gg.Context, f *frameState) {
+	samples := v.frameSamples(f.specIdx)
+	var sumSquares float64
+	for _, sample := range samples {
+		sumSquares += sample * sample
+	}
+	var level float64
+	if len(samples) > 0 && sumSquares > 0 {
+		level = v.scaleAmplitude(math.Sqrt(sumSquares / float64(len(samples))))
+		if v.config.Sensitivity > 0 {
+			level *= v.config.Sensitivity
+		}
+		level = math.Max(0, math.Min(1, level))
+	}
+	
+	count := vuMeterSegments
+	if v.config.SegmentedBars && v.config.SegmentCount > 0 {
+		count = v.config.SegmentCount
+	}
+	
+	margin := 50.0
+	meterWidth := float64(v.config.Width) - 2*margin
+	meterHeight := float64(v.config.Height) / 6
+	top := (float64(v.config.Height) - meterHeight) / 2
+	segmentWidth := meterWidth / float64(count)
+	gap := math.Max(1, segmentWidth*0.2)
+	
+	for j := 0; j < count; j++ {
+		position := float64(j+1) / float64(count)
+		segmentColor := v.getColor(position, f.hueShift)
+		if float64(j)*segmentWidth+segmentWidth/2 > level*meterWidth {
+			segmentColor = shadeColor(segmentColor, -0.85)
+		}
+		
+		dc.SetColor(segmentColor)
+		dc.DrawRectangle(margin+float64(j)*segmentWidth+gap/2, top, segmentWidth-gap, meterHeight)
+		dc.Fill()
+	}
+}
+
+// hbarLabelWidth is the width in pixels of the column left of the hbars
+// visualization holding each bar's frequency label
+const hbarLabelWidth = 64.0
+
+// drawHBars draws each band as a horizontal bar, lowest band at the top,
+// growing right from a column of band center frequency labels like a row of
+// mixer channel meters
+func (v *Visualizer) drawHBars(dc *gg.Context, f *frameState) {
+	magnitudes, peaks := f.magnitudes, f.peaks
+	slot := float64(v.config.Height) / float64(len(magnitudes))
+	thickness := slot * (1 - v.config.BarGap)
+	maxLength := float64(v.config.Width) - hbarLabelWidth - 20
+	length := func(magnitude float64) float64 {
+		return math.Min(v.config.MinBarHeight+v.curveHeight(magnitude)*maxLength, maxLength)
+	}
+	
+	// Label every row that leaves room for the 13px text
+	labelEvery := int(math.Ceil(16 / slot))
+	
+	for i, magnitude := range magnitudes {
+		y := float64(i)*slot + (slot-thickness)/2
+		
+		dc.SetColor(v.getBarColor(i, magnitude, f.hueShift))
+		v.drawBarShape(dc, hbarLabelWidth, y, length(magnitude), thickness, 0)
+		dc.Fill()
+		
+		if i < len(peaks) {
+			dc.SetColor(v.getBarColor(i, peaks[i], f.hueShift))
+			dc.DrawRectangle(hbarLabelWidth+length(peaks[i]), y, peakCapHeight, thickness)
+			dc.Fill()
+		}
+		
+		if i%labelEvery == 0 {
+			label := freqLabel(v.barFreqs[i])
+			dc.SetRGBA(1, 1, 1, 0.9)
+			dc.DrawStringAnchored(label, hbarLabelWidth-8, math.Max(y+thickness/2, 8), 1, 0.5)
+		}
+	}
+}
+
+// freqLabel formats a frequency in Hz for an axis label, as whole hertz or in
+// kHz with one decimal like "1.2k"
+func freqLabel(freq float64) string {
+	if freq >= 1000 {
+		return strconv.FormatFloat(math.Round(freq/100)/10, 'f', -1, 64) + "k"
+	}
+	return strconv.FormatFloat(math.Round(freq), 'f', -1, 64)
+}
+
+// freqAxisTicks are the frequencies the frequency axis labels when they fall
+// within the analysed band
+var freqAxisTicks = []float64{50, 100, 200, 500, 1000, 2000, 5000, 10000, 20000}
+
+// freqAxisTickLength is the length in pixels of the frequency axis ticks
+const freqAxisTickLength = 8.0
+
+// drawFreqAxis draws tick marks and Hz labels along the edge the bars grow
+// from, placing each tick within the bar whose band contains its frequency
+func (v *Visualizer) drawFreqAxis(dc *gg.Context) {
+	edges := v.bandEdges()
+	height := v.barBaseline()
+	
+	// Anchor labels on the side of the tick facing into the frame
+	ax, ay := 0.5, 0.0
+	switch v.config.Orientation {
+	case "top-down":
+		ay = 1
+	case "left-right":
+		ax, ay = 0, 0.5
+	case "right-left":
+		ax, ay = 1, 0.5
+	}
+	
+	for _, freq := range freqAxisTicks {
+		i := sort.SearchFloat64s(edges, freq) - 1
+		if i < 0 || i >= v.config.BarCount {
+			continue
+		}
+		t := (freq - edges[i]) / (edges[i+1] - edges[i])
+		x := v.barPositions[i] + t*v.barWidth
+		
+		// Symmetric bars put each frequency on both sides of the center
+		xs := []float64{x}
+		if v.config.Symmetric {
+			center := float64(v.config.Width) / 2
+			xs = []float64{center - x/2, center + x/2}
+		}
+		
+		label := freqLabel(freq)
+		for _, x := range xs {
+			dc.SetRGBA(1, 1, 1, 0.8)
+			dc.DrawRectangle(v.orientRect(x-0.5, height-freqAxisTickLength, 1, freqAxisTickLength))
+			dc.Fill()
+			
+			lx, ly, _, _ := v.orientRect(x, height-freqAxisTickLength-4, 0, 0)
+			dc.SetRGBA(0, 0, 0, 0.6)
+			dc.DrawStringAnchored(label, lx+1, ly+1, ax, ay)
+			dc.SetRGBA(1, 1, 1, 0.9)
+			dc.DrawStringAnchored(label, lx, ly, ax, ay)
+		}
+	}
+}
+
+// clampUnit limits a sample to the -1..1 range
+func clampUnit(sample float64) float64 {
+	return math.Max(-1, math.Min(1, sample))
+}
+
+// titleMargin is the gap in pixels between the title and the frame edge
+const titleMargin = 24.0
+
+// basicFontSize is the point size of gg's built-in 7x13 font, used to scale
+// it to TitleSize when no TTF is configured
+const basicFontSize = 13.0
+
+// drawTitle draws the title text anchored at the configured position
+func (v *Visualizer) drawTitle(dc *gg.Context) {
+	size := v.config.TitleSize
+	if size <= 0 {
+		size = 36
+	}
+	
+	width := float64(v.config.Width)
+	height := float64(v.config.Height)
+	x, y, ax, ay := titleMargin, titleMargin, 0.0, 1.0
+	switch v.config.TitlePosition {
+	case "top-right":
+		x, ax = width-titleMargin, 1
+	case "bottom-left":
+		y, ay = height-titleMargin, 0
+	case "bottom-right":
+		x, y, ax, ay = width-titleMargin, height-titleMargin, 1, 0
+	case "center":
+		x, y, ax, ay = width/2, height/2, 0.5, 0.5
+	}
+	
+	textColor, err := parseHexColor(v.config.TitleColor)
+	if err != nil {
+		textColor = color.White
+	}
+	
+	dc.Push()
+	defer dc.Pop()
+	dc.SetColor(textColor)
+	if v.titleFont != nil {
+		dc.SetFontFace(truetype.NewFace(v.titleFont, &truetype.Options{Size: size}))
+	} else {
+		// The built-in font has a fixed size, so scale it around the anchor
+		dc.ScaleAbout(size/basicFontSize, size/basicFontSize, x, y)
+	}
+	dc.DrawStringAnchored(v.config.TitleText, x, y, ax, ay)
+}
@@ -0,0 +1,144 @@
+package audiospectrum
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+// TestDrawShortMagnitudes feeds the types that space points across the
+// width fewer levels than they need for a step, which must draw nothing
+// rather than divide by zero or index past the end
+func TestDrawShortMagnitudes(t *testing.T) {
+	tests := []struct {
+		vizType    VisType
+		magnitudes []float64
+	}{
+		{VisTypeLine, nil},
+		{VisTypeLine, []float64{}},
+		{VisTypeLine, []float64{0.5}},
+		{VisTypeWave, nil},
+		{VisTypeWave, []float64{}},
+		{VisTypeWave, []float64{0.5}},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.VisType = tt.vizType
+		config.Width = 320
+		config.Height = 240
+		v := NewVisualizer(newVisualizerConfig(config))
+		dc := gg.NewContext(config.Width, config.Height)
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s with %d magnitudes panicked: %v", tt.vizType, len(tt.magnitudes), r)
+				}
+			}()
+			v.drawVisualization(dc, &frameState{magnitudes: tt.magnitudes})
+		}()
+	}
+}
+
+// TestManyBarsSmallFrame draws a few hundred bars, odd counts included, on a
+// 640 pixel wide frame. The angular types don't lay bars out across the width,
+// so they must not get bar slots, and every type must draw its loud bands.
+func TestManyBarsSmallFrame(t *testing.T) {
+	for _, vizType := range []VisType{VisTypeCircular, VisTypeRadial, VisTypeBars} {
+		for _, barCount := range []int{199, 200, 256} {
+			config := DefaultConfig()
+			config.VisType = vizType
+			config.Width = 640
+			config.Height = 360
+			config.BarCount = barCount
+			if err := config.Validate(); err != nil {
+				t.Fatalf("%s with %d bars: %v", vizType, barCount, err)
+			}
+
+			v, err := NewVisualizerChecked(newVisualizerConfig(config))
+			if err != nil {
+				t.Fatalf("%s with %d bars: %v", vizType, barCount, err)
+			}
+			if !usesBarSlots(string(vizType)) && v.barWidth != 0 {
+				t.Errorf("%s laid out %v pixel bar slots it never uses", vizType, v.barWidth)
+			}
+
+			levels := make([]float64, barCount)
+			for i := range levels {
+				levels[i] = 0.8
+			}
+			v.spectrumData = [][]float64{levels}
+			loud := v.generateFrame(0).Image().(*image.RGBA)
+			v.spectrumData = nil
+			silent := v.generateFrame(0).Image().(*image.RGBA)
+			if bytes.Equal(loud.Pix, silent.Pix) {
+				t.Errorf("%s with %d bars drew nothing for loud bands", vizType, barCount)
+			}
+		}
+	}
+}
+
+// TestMirrorGlow checks the glow outline of a loud mirror bar is actually
+// painted, by reading a pixel just outside the bar where only it can reach
+func TestMirrorGlow(t *testing.T) {
+	tests := []struct {
+		name         string
+		magnitude    float64
+		glowOpacity  float64
+		cornerRadius float64
+		wantGlow     bool
+	}{
+		{"loud", 1, 0.3, 0, true},
+		{"loud rounded", 1, 0.3, 6, true},
+		{"quiet", 0.3, 0.3, 0, false},
+		{"glow off", 1, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.VisType = VisTypeMirror
+		config.Width = 320
+		config.Height = 240
+		config.GlowOpacity = tt.glowOpacity
+		config.BarCornerRadius = tt.cornerRadius
+		v := NewVisualizer(newVisualizerConfig(config))
+
+		// Draw on a transparent frame so anything painted has alpha
+		dc := gg.NewContext(config.Width, config.Height)
+		x, barWidth := 100.0, 20.0
+		v.drawMirrorBar(dc, &frameState{}, 0, tt.magnitude, x, barWidth)
+
+		_, _, _, a := dc.Image().At(int(x)-2, config.Height/2).RGBA()
+		if gotGlow := a > 0; gotGlow != tt.wantGlow {
+			t.Errorf("%s: glow painted %v, want %v", tt.name, gotGlow, tt.wantGlow)
+		}
+	}
+}
+
+// TestSpectrogramSilenceGate checks levels below SilenceThreshold draw in the
+// silent color in every spectrogram column, not just the current one
+func TestSpectrogramSilenceGate(t *testing.T) {
+	config := DefaultConfig()
+	config.VisType = VisTypeSpectrogram
+	config.Width = 320
+	config.Height = 240
+	config.BarCount = 8
+	config.SilenceThreshold = 0.1
+	v := NewVisualizer(newVisualizerConfig(config))
+
+	quiet := make([]float64, config.BarCount)
+	for i := range quiet {
+		quiet[i] = 0.05
+	}
+	v.spectrumData = [][]float64{quiet, quiet, quiet}
+	got := v.generateFrame(2).Image().(*image.RGBA)
+
+	v.spectrumData = [][]float64{make([]float64, config.BarCount), make([]float64, config.BarCount), make([]float64, config.BarCount)}
+	want := v.generateFrame(2).Image().(*image.RGBA)
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Error("levels under SilenceThreshold drew differently from silence")
+	}
+}
@@ -0,0 +1,56 @@
+package audiospectrum
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidConfig matches every ConfigError with errors.Is, for callers that
+// only need to tell a bad option apart from a failed render
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// ConfigError reports a Config option that failed validation. Field is the
+// name of the Config field at fault (for checks involving several fields,
+// the one the message is about) and Value its value, so UIs can attach the
+// error to the matching form input; Reason is the English message.
+// Use errors.As to get at it through the "invalid configuration" wrapping
+// added by Generate.
+type ConfigError struct {
+	Field  string
+	Value  any
+	Reason string
+	Err    error
+}
+
+// Error returns the reason, which already names the option
+func (e *ConfigError) Error() string {
+	return e.Reason
+}
+
+// Unwrap returns the underlying error, such as a color or font parse error
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidConfig
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// invalidConfig wraps a validation error the way every entry point reports
+// it, so rejected options all read "invalid configuration: <reason>"
+func invalidConfig(err error) error {
+	return fmt.Errorf("invalid configuration: %w", err)
+}
+
+// invalidField builds a ConfigError for field, formatting the reason like
+// fmt.Errorf so a %w verb keeps the underlying error unwrappable
+func invalidField(field string, value any, format string, args ...any) *ConfigError {
+	err := fmt.Errorf(format, args...)
+	return &ConfigError{
+		Field:  field,
+		Value:  value,
+		Reason: err.Error(),
+		Err:    errors.Unwrap(err),
+	}
+}
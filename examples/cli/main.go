@@ -13,18 +13,114 @@ func main() {
 	var (
 		outputFile   = flag.String("o", "spectrum_video.mp4", "Output video file")
 		fps          = flag.Int("f", 30, "Frames per second")
+		startTime    = flag.Float64("ss", 0, "Start offset into the audio in seconds")
 		duration     = flag.Float64("d", 0, "Duration in seconds (0 for full audio)")
+		loopTo       = flag.Float64("loopto", 0, "Repeat the audio and visualization to this many seconds (0 for no looping)")
+		pad          = flag.Bool("pad", false, "Pad with silence when the audio is shorter than -d")
 		bars         = flag.Int("b", 32, "Number of frequency bars")
 		colorScheme  = flag.String("c", "rainbow", "Color scheme (rainbow, fire, ocean, purple, neon, monochrome, sunset, forest, ice, lava, retro, cosmic, pastel, matrix, white)")
-		vizType      = flag.String("t", "bars", "Visualization type (bars, circular, wave, radial, line, dots, mirror, spiral)")
-		bgColor      = flag.String("bg", "green", "Background color (green, blue, magenta, black, white, gray)")
+		vizType      = flag.String("t", "bars", "Visualization type (bars, circular, wave, radial, line, dots, mirror, spiral, spectrogram, oscilloscope, circular-wave, vu-meter, hbars, area, butterfly)")
+		bgColor      = flag.String("bg", "green", "Background color (green, blue, magenta, black, white, gray or #RRGGBB)")
 		width        = flag.Int("w", 1280, "Video width")
 		height       = flag.Int("h", 720, "Video height")
-		processType  = flag.String("method", "parallel", "Processing method (fast, parallel)")
+		processType  = flag.String("method", "parallel", "Processing method (fast, parallel, pipe, parallel-pipe)")
+		workers      = flag.Int("workers", 0, "Render goroutines for the parallel methods (0 = one per CPU)")
+		ffmpegPath   = flag.String("ffmpeg", "ffmpeg", "Path to the ffmpeg binary")
+		ffprobePath  = flag.String("ffprobe", "ffprobe", "Path to the ffprobe binary")
+		sampleRate   = flag.Int("rate", 22050, "Analysis sample rate (22050, 44100, 48000)")
+		sampleFormat = flag.String("samplefmt", "f32", "PCM format decoded for analysis (f32, s16)")
+		hopLength    = flag.Int("hop", 0, "Samples between analysis windows (0 = one per video frame)")
+		ampScale     = flag.String("scale", "log", "Amplitude scale (linear, log, db)")
+		dbFloor      = flag.Float64("dbfloor", -60, "Lowest level in dB shown by the db amplitude scale")
+		logMul       = flag.Float64("logmul", 1000, "Log amplitude scale multiplier (larger lifts quiet levels)")
+		logDiv       = flag.Float64("logdiv", 3, "Log amplitude scale divisor (smaller makes bars taller)")
+		binAgg       = flag.String("agg", "average", "How FFT bins combine into a bar (average, max, sum)")
+		freqScale    = flag.String("freqscale", "log", "Bar frequency spacing (log, linear, mel)")
+		weighting    = flag.String("weight", "none", "Loudness weighting of FFT bins (none, a-weight)")
+		downmix      = flag.Bool("downmix", true, "Weighted mono downmix for surround input (LFE dropped)")
+		fracBins     = flag.Bool("fracbins", false, "Weight partially covered FFT bins instead of truncating bar edges")
+		smoothing    = flag.Float64("smooth", 0.15, "Frame-to-frame smoothing (0 = none, 1 = maximum)")
+		sensitivity  = flag.Float64("sens", 1, "Bar height multiplier (2 = roughly twice as responsive)")
+		noiseGate    = flag.Float64("gate", 0, "Flatten bars below this level, 0-1 (0 disables)")
+		normalize    = flag.Bool("normalize", false, "Rescale the track so its loudest moment reaches full bar height")
+		agc          = flag.Bool("agc", false, "Automatic gain against the recent loudest level (not with -normalize)")
+		agcWindow    = flag.Float64("agcwindow", 3, "Seconds of history the automatic gain looks back over")
+		barGap       = flag.Float64("gap", 0.2, "Fraction of each bar slot left as space (0 = touching bars)")
+		cornerRadius = flag.Float64("radius", 0, "Bar corner radius in pixels (0 for square bars)")
+		minBar       = flag.Float64("minbar", 5, "Height in pixels every bar keeps in silence (0 hides silent bars)")
+		silence      = flag.Float64("silence", 0.01, "Draw bands below this level, 0-1, as silent")
+		heightCurve  = flag.String("curve", "linear", "Bar height curve (linear, sqrt, square)")
+		reflection   = flag.Bool("reflect", false, "Draw a fading reflection of the bars below them")
+		reflectAlpha = flag.Float64("reflectopacity", 0.4, "Opacity of the bar reflection, 0-1")
+		orientation  = flag.String("orient", "bottom-up", "Bars orientation (bottom-up, top-down, left-right, right-left)")
+		freqAxis     = flag.Bool("axis", false, "Label band frequencies along the base of the bars")
+		symmetric    = flag.Bool("sym", false, "Center the low frequencies and mirror the bars out to both edges")
+		bevel        = flag.Bool("bevel", false, "Draw bars with a lighted 3D bevel")
+		barGradient  = flag.Bool("gradient", false, "Fill bars with a gradient from their base color to their level's color")
+		peakHold     = flag.Bool("peaks", false, "Draw falling peak-hold caps on bars")
+		peakDecay    = flag.Float64("peakdecay", 0.02, "Amount peak caps fall per frame")
+		segments     = flag.Int("segments", 0, "Draw bars as this many LED segments (0 for solid bars)")
+		smoothLine   = flag.Bool("smoothline", false, "Draw the line visualization as a smooth curve")
+		lineCap      = flag.String("linecap", "round", "Line end style (round, butt, square)")
+		interp       = flag.String("interp", "none", "Points between bands in line, wave and area (none, linear, cubic)")
+		spiralTurns  = flag.Float64("turns", 2, "Turns of the spiral visualization (up to 20)")
+		rotation     = flag.Float64("rotate", 0, "Degrees per second the circular, radial and spiral types spin")
+		dotsMax      = flag.Int("dots", 10, "Dots a full-level band stacks above its base dot in the dots visualization")
+		dotSize      = flag.Float64("dotsize", 1, "Dot radius multiplier in the dots visualization")
+		visualSpeed  = flag.Float64("vspeed", 1, "Visual playback speed; audio stays at normal speed (0.5 = slow motion)")
+		colorMode    = flag.String("colormode", "magnitude", "Bar coloring (magnitude, frequency)")
+		hueSpan      = flag.Float64("huespan", 360, "Degrees of hue spread across the bars in frequency color mode")
+		baseHue      = flag.Float64("basehue", 0, "Hue in degrees of the lowest band in frequency color mode")
+		colorCycle   = flag.Float64("cycle", 0, "Degrees per second to rotate the palette's hue (0 disables)")
+		accentColor  = flag.String("accent", "", "Hex color for bars above the accent threshold, e.g. #ffffff")
+		accentLevel  = flag.Float64("accentlevel", 0.8, "Magnitude above which bars use the accent color")
+		glowLevel    = flag.Float64("glowlevel", 0.5, "Magnitude above which parts glow")
+		glowOpacity  = flag.Float64("glow", 0.3, "Glow opacity, 0-1 (0 disables the glow)")
+		glowColor    = flag.String("glowcolor", "#ffffff", "Glow color (#RRGGBB, or scheme for each bar's own color)")
+		shadow       = flag.Bool("shadow", false, "Draw a soft drop shadow behind the visualization")
+		shadowColor  = flag.String("shadowcolor", "#000000", "Shadow color (#RRGGBB)")
+		shadowOffset = flag.Float64("shadowoffset", 6, "Pixels the shadow is shifted right and down")
+		shadowBlur   = flag.Float64("shadowblur", 8, "Shadow blur radius in pixels")
+		bgImage      = flag.String("bgimage", "", "Background image file (overrides -bg)")
+		bgFit        = flag.String("bgfit", "cover", "Background image fit (fill, contain, cover)")
+		bgBlur       = flag.Float64("bgblur", 0, "Background image blur radius in pixels")
+		autoBg       = flag.Bool("autobg", false, "Use the input's embedded cover art as a blurred background")
+		bgTop        = flag.String("bgtop", "", "Gradient background top color (#RRGGBB, use with -bgbottom)")
+		bgBottom     = flag.String("bgbottom", "", "Gradient background bottom color (#RRGGBB, use with -bgtop)")
+		watermark    = flag.String("watermark", "", "Logo image (PNG or JPEG) drawn on every frame")
+		wmPosition   = flag.String("wmpos", "bottom-right", "Watermark position (top-left, top-right, bottom-left, bottom-right, center)")
+		wmOpacity    = flag.Float64("wmopacity", 1, "Watermark opacity (0-1)")
+		title        = flag.String("title", "", "Title text drawn on every frame, e.g. \"Song - Artist\"")
+		titleFont    = flag.String("titlefont", "", "TTF font for the title (default: built-in font)")
+		titleSize    = flag.Float64("titlesize", 36, "Title font size in points")
+		titleColor   = flag.String("titlecolor", "#ffffff", "Title color (#RRGGBB)")
+		titlePos     = flag.String("titlepos", "top-left", "Title position (top-left, top-right, bottom-left, bottom-right, center)")
+		centerImage  = flag.String("center", "", "Image (PNG or JPEG) shown in a circle inside the circular visualizations")
+		centerRadius = flag.Float64("centerradius", 0, "Radius in pixels of the circular visualizations' center (0 for 80)")
+		centerPulse  = flag.Float64("pulse", 0, "How much larger the center image grows at full bass (0-1)")
+		loopCount    = flag.Int("loop", 0, "GIF/WebP play count (0 loops forever)")
+		videoCodec   = flag.String("vcodec", "libx264", "Video codec")
+		outFormat    = flag.String("format", "", "Output container (mp4, webm, gif, webp); empty picks by the -o extension")
+		videoCRF     = flag.Int("crf", 23, "Video quality, 0-51, lower is better (0 for encoder default)")
+		videoPreset  = flag.String("preset", "ultrafast", "Encoder preset (ultrafast ... veryslow)")
+		audioBitrate = flag.String("ab", "192k", "Audio bitrate")
+		hwAccel      = flag.String("hwaccel", "none", "Hardware encoder (none, nvenc, videotoolbox, qsv)")
+		frameFormat  = flag.String("framefmt", "png", "Temporary frame format for fast and parallel (png, jpeg)")
+		frameQuality = flag.Int("framequality", 90, "JPEG frame quality, 1-100")
+		pixelFormat  = flag.String("pixfmt", "yuv420p", "Output pixel format (yuv420p, yuv422p, yuv444p, yuv420p10le, yuv422p10le)")
+		profile      = flag.String("profile", "", "H.264 profile (baseline, main, high, high10, high422, high444)")
+		level        = flag.String("level", "", "H.264 level, e.g. 4.1")
+		noOverwrite  = flag.Bool("nooverwrite", false, "Fail instead of replacing an existing output file")
+		autoInc      = flag.Bool("autoinc", false, "Write to name_1, name_2, ... instead of replacing an existing output")
+		manifest     = flag.String("manifest", "", "Write a JSON render manifest to this file")
+		exportData   = flag.String("export", "", "Write per-frame bar levels to this file (.csv or .json)")
+		cacheDir     = flag.String("cache", "", "Directory for cached frames; re-encodes reuse them instead of re-rendering")
+		overlay      = flag.Bool("overlay", false, "Overlay the spectrum onto the input video instead of a background")
+		framesDir    = flag.String("frames", "", "Write a PNG frame sequence to this directory instead of a video")
 	)
 	
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] input.mp3\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] input.mp3 (- reads stdin)\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nUltra-fast audio spectrum video generator\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
@@ -49,6 +145,7 @@ func main() {
 		InputFile:    inputFile,
 		OutputFile:   *outputFile,
 		FPS:          *fps,
+		StartTime:    *startTime,
 		Duration:     *duration,
 		BarCount:     *bars,
 		ColorScheme:  audiospectrum.ColorScheme(*colorScheme),
@@ -57,10 +154,148 @@ func main() {
 		Width:        *width,
 		Height:       *height,
 		ProcessType:  audiospectrum.ProcessType(*processType),
+		Workers:      *workers,
+
+		FrameFormat:  audiospectrum.FrameFormat(*frameFormat),
+		FrameQuality: *frameQuality,
+
+		FFmpegPath:  *ffmpegPath,
+		FFprobePath: *ffprobePath,
+
+		SampleRate:     *sampleRate,
+		SampleFormat:   audiospectrum.SampleFormat(*sampleFormat),
+		HopLength:      *hopLength,
+		AmplitudeScale: audiospectrum.AmplitudeScale(*ampScale),
+		DBFloor:        *dbFloor,
+		Smoothing:      *smoothing,
+		Sensitivity:    *sensitivity,
+		NoiseGate:      *noiseGate,
+		BinAggregation: audiospectrum.BinAggregation(*binAgg),
+		FreqScale:      audiospectrum.FreqScale(*freqScale),
+		Weighting:      audiospectrum.Weighting(*weighting),
+		FractionalBins: *fracBins,
+
+		LogScaleMultiplier: *logMul,
+		LogScaleDivisor:    *logDiv,
+
+		Normalize: *normalize,
+		AGC:       *agc,
+		AGCWindow: *agcWindow,
+
+		SurroundDownmix: *downmix,
+
+		BarGap:    *barGap,
+		BarBevel:  *bevel,
+		PeakHold:  *peakHold,
+		PeakDecay: *peakDecay,
+
+		BarGradient: *barGradient,
+
+		BarCornerRadius: *cornerRadius,
+		MinBarHeight:    *minBar,
+
+		SilenceThreshold: *silence,
+		HeightCurve:      audiospectrum.HeightCurve(*heightCurve),
+
+		Reflection:        *reflection,
+		ReflectionOpacity: *reflectAlpha,
+
+		Orientation:  audiospectrum.Orientation(*orientation),
+		ShowFreqAxis: *freqAxis,
+		Symmetric:    *symmetric,
+
+		SegmentedBars: *segments > 0,
+		SegmentCount:  *segments,
+
+		SmoothLine: *smoothLine,
+		LineCap:    audiospectrum.LineCap(*lineCap),
+
+		Interpolation: audiospectrum.Interpolation(*interp),
+
+		SpiralTurns:  *spiralTurns,
+		DotsMaxCount: *dotsMax,
+		DotSize:      *dotSize,
+
+		RotationSpeed: *rotation,
+
+		VisualSpeed: *visualSpeed,
+
+		ColorMode: audiospectrum.ColorMode(*colorMode),
+		HueSpan:   *hueSpan,
+		BaseHue:   *baseHue,
+
+		ColorCycleSpeed: *colorCycle,
+
+		AccentColor:     *accentColor,
+		AccentThreshold: *accentLevel,
+
+		GlowThreshold: *glowLevel,
+		GlowOpacity:   *glowOpacity,
+		GlowColor:     *glowColor,
+
+		Shadow:       *shadow,
+		ShadowColor:  *shadowColor,
+		ShadowOffset: *shadowOffset,
+		ShadowBlur:   *shadowBlur,
+
+		BackgroundImage: *bgImage,
+		BackgroundFit:   audiospectrum.BackgroundFit(*bgFit),
+		BackgroundBlur:  *bgBlur,
+		AutoBackground:  *autoBg,
+
+		BGGradientTop:    *bgTop,
+		BGGradientBottom: *bgBottom,
+
+		WatermarkFile:     *watermark,
+		WatermarkPosition: audiospectrum.Position(*wmPosition),
+		WatermarkOpacity:  *wmOpacity,
+
+		TitleText:     *title,
+		TitleFont:     *titleFont,
+		TitleSize:     *titleSize,
+		TitleColor:    *titleColor,
+		TitlePosition: audiospectrum.Position(*titlePos),
+
+		CenterImage:  *centerImage,
+		CenterRadius: *centerRadius,
+		CenterPulse:  *centerPulse,
+
+		OutputFormat: audiospectrum.OutputFormat(*outFormat),
+		LoopCount:    *loopCount,
+		VideoCodec:   *videoCodec,
+		VideoCRF:     *videoCRF,
+		VideoPreset:  *videoPreset,
+		AudioBitrate: *audioBitrate,
+		HWAccel:      audiospectrum.HWAccel(*hwAccel),
+
+		PixelFormat: *pixelFormat,
+		Profile:     *profile,
+		Level:       *level,
+
+		NoOverwrite:   *noOverwrite,
+		AutoIncrement: *autoInc,
+
+		CacheDir:      *cacheDir,
+		WriteManifest: *manifest,
+		ExportData:    *exportData,
+
+		OverlayOnInput: *overlay,
+		LoopToDuration: *loopTo,
+		PadToDuration:  *pad,
 	}
 	
-	// Generate video
-	if err := audiospectrum.Generate(config); err != nil {
+	// Generate video (or frames with -frames), streaming the audio from stdin for "-"
+	generate := audiospectrum.Generate
+	if *framesDir != "" {
+		generate = func(config *audiospectrum.Config) error {
+			return audiospectrum.GenerateFrames(config, *framesDir)
+		}
+	} else if inputFile == "-" {
+		generate = func(config *audiospectrum.Config) error {
+			return audiospectrum.GenerateFromReader(os.Stdin, config)
+		}
+	}
+	if err := generate(config); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,121 @@
+package audiospectrum
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LevelData is the per-frame bar levels of a render, written by ExportData
+// for driving lights or other visuals in sync with the video. As in Manifest,
+// its JSON keys are the Go field names.
+type LevelData struct {
+	FPS         int
+	BarCount    int
+	Frequencies []float64 // Center frequency of each bar in Hz
+	Frames      []LevelFrame
+}
+
+// LevelFrame is the 0-1 level of every bar at one video frame
+type LevelFrame struct {
+	Time   float64 // Seconds from the start of the video
+	Levels []float64
+}
+
+// exportLevelData writes the level data of a finished render to path when
+// Config.ExportData names one, and reports where it went
+func (v *Visualizer) exportLevelData(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := v.writeLevelData(path); err != nil {
+		return fmt.Errorf("failed to export level data: %w", err)
+	}
+	fmt.Printf("Level data: %s\n", path)
+	return nil
+}
+
+// writeLevelData writes the analysed level of every bar for every video frame
+// to path, as CSV when it ends in .csv and JSON otherwise. A render served
+// from the frame cache skips analysis, so the audio is analysed here then.
+func (v *Visualizer) writeLevelData(path string) error {
+	if v.spectrumData == nil {
+		if err := v.loadAudio(); err != nil {
+			return fmt.Errorf("loading audio: %w", err)
+		}
+		if err := v.precomputeSpectrum(); err != nil {
+			return fmt.Errorf("computing spectrum: %w", err)
+		}
+	}
+	
+	data := v.levelData()
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		return writeLevelCSV(path, data)
+	}
+	
+	out, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0644)
+}
+
+// levelData collects the levels each video frame is drawn from, after
+// SilenceThreshold, rounded to four decimals to keep long exports compact
+func (v *Visualizer) levelData() *LevelData {
+	data := &LevelData{
+		FPS:         v.config.FPS,
+		BarCount:    v.config.BarCount,
+		Frequencies: make([]float64, len(v.barFreqs)),
+		Frames:      make([]LevelFrame, v.totalFrames),
+	}
+	for i, freq := range v.barFreqs {
+		data.Frequencies[i] = math.Round(freq*10) / 10
+	}
+	
+	for frame := range data.Frames {
+		row := v.newFrameState(frame).magnitudes
+		levels := make([]float64, len(row))
+		for i, level := range row {
+			levels[i] = math.Round(level*1e4) / 1e4
+		}
+		data.Frames[frame] = LevelFrame{Time: float64(frame) / float64(v.config.FPS), Levels: levels}
+	}
+	return data
+}
+
+// writeLevelCSV writes level data as CSV with a time column followed by one
+// column per bar, headed by its center frequency
+func writeLevelCSV(path string, data *LevelData) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	
+	w := csv.NewWriter(f)
+	header := []string{"time"}
+	for _, freq := range data.Frequencies {
+		header = append(header, strconv.FormatFloat(freq, 'f', -1, 64))
+	}
+	w.Write(header)
+	
+	for _, frame := range data.Frames {
+		record := []string{strconv.FormatFloat(frame.Time, 'f', 4, 64)}
+		for _, level := range frame.Levels {
+			record = append(record, strconv.FormatFloat(level, 'f', -1, 64))
+		}
+		w.Write(record)
+	}
+	
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -4,10 +4,8 @@ go 1.21
 
 require (
 	github.com/fogleman/gg v1.3.0
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/mjibson/go-dsp v0.0.0-20180508042940-11479a337f12
 )
 
-require (
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	golang.org/x/image v0.14.0 // indirect
-)
+require golang.org/x/image v0.14.0 // indirect
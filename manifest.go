@@ -0,0 +1,163 @@
+package audiospectrum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Manifest describes exactly how a video was rendered. It is written as JSON
+// after a successful render when Config.WriteManifest is set. Fields keep
+// their Go names as keys, matching the embedded VisualizerConfig, and
+// Duration and RenderTime are in seconds.
+type Manifest struct {
+	InputFile     string
+	InputSHA256   string
+	OutputFile    string
+	FFmpegVersion string
+	Duration      float64
+	FrameCount    int
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	RenderTime    float64
+	Config        VisualizerConfig
+}
+
+// writeManifest writes the render manifest for a finished render to path
+func (v *Visualizer) writeManifest(path string, startedAt, finishedAt time.Time) error {
+	inputHash, err := fileSHA256(v.config.InputFile)
+	if err != nil {
+		return fmt.Errorf("hashing input: %w", err)
+	}
+	
+	manifest := Manifest{
+		InputFile:     v.config.InputFile,
+		InputSHA256:   inputHash,
+		OutputFile:    v.output(),
+		FFmpegVersion: v.ffmpegVersion(),
+		Duration:      v.duration,
+		FrameCount:    v.totalFrames,
+		StartedAt:     startedAt,
+		FinishedAt:    finishedAt,
+		RenderTime:    finishedAt.Sub(startedAt).Seconds(),
+		Config:        v.effectiveConfig(),
+	}
+	
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// effectiveConfig returns the configuration with every zero value that the
+// renderer treats as a default replaced by the value actually used
+func (v *Visualizer) effectiveConfig() VisualizerConfig {
+	c := *v.config
+	c.FFmpegPath = v.ffmpegPath()
+	c.FFprobePath = v.ffprobePath()
+	c.VideoCodec = orDefault(v.videoEncoder, orDefault(c.VideoCodec, "libx264"))
+	c.VideoPreset = orDefault(c.VideoPreset, "ultrafast")
+	c.OutputFormat = v.outputFormat()
+	c.AudioBitrate = orDefault(c.AudioBitrate, "192k")
+	c.PixelFormat = orDefault(c.PixelFormat, "yuv420p")
+	c.AmplitudeScale = orDefault(c.AmplitudeScale, "log")
+	c.BinAggregation = orDefault(c.BinAggregation, "average")
+	c.FreqScale = orDefault(c.FreqScale, "log")
+	c.Weighting = orDefault(c.Weighting, "none")
+	c.BackgroundFit = orDefault(c.BackgroundFit, "cover")
+	c.ColorMode = orDefault(c.ColorMode, "magnitude")
+	c.Orientation = orDefault(c.Orientation, "bottom-up")
+	c.LineCap = orDefault(c.LineCap, "round")
+	c.WatermarkPosition = orDefault(c.WatermarkPosition, "bottom-right")
+	c.TitlePosition = orDefault(c.TitlePosition, "top-left")
+	c.TitleColor = orDefault(c.TitleColor, "#ffffff")
+	c.GlowColor = orDefault(c.GlowColor, "#ffffff")
+	c.ShadowColor = orDefault(c.ShadowColor, "#000000")
+	
+	if c.SampleRate <= 0 {
+		c.SampleRate = 22050
+	}
+	if c.HopLength <= 0 {
+		c.HopLength = c.SampleRate / c.FPS
+	}
+	if c.DBFloor == 0 {
+		c.DBFloor = -60
+	}
+	if c.LogScaleMultiplier == 0 {
+		c.LogScaleMultiplier = 1000
+	}
+	if c.LogScaleDivisor == 0 {
+		c.LogScaleDivisor = 3
+	}
+	if c.Sensitivity <= 0 {
+		c.Sensitivity = 1
+	}
+	if c.SpiralTurns <= 0 {
+		c.SpiralTurns = 2
+	}
+	if c.DotsMaxCount <= 0 {
+		c.DotsMaxCount = 10
+	}
+	if c.DotSize <= 0 {
+		c.DotSize = 1
+	}
+	if c.HueSpan <= 0 {
+		c.HueSpan = 360
+	}
+	if c.TitleSize <= 0 {
+		c.TitleSize = 36
+	}
+	if c.WatermarkOpacity <= 0 || c.WatermarkOpacity > 1 {
+		c.WatermarkOpacity = 1
+	}
+	if c.Workers <= 0 && (c.ProcessType == "parallel" || c.ProcessType == "parallel-pipe") {
+		c.Workers = runtime.NumCPU()
+	}
+	c.SampleFormat = orDefault(c.SampleFormat, "f32")
+	c.HeightCurve = orDefault(c.HeightCurve, "linear")
+	c.Interpolation = orDefault(c.Interpolation, "none")
+	if c.FrameFormat == "" {
+		c.FrameFormat = FrameFormatPNG
+	}
+	if c.FrameQuality <= 0 && c.FrameFormat == FrameFormatJPEG {
+		c.FrameQuality = 90
+	}
+	if c.ReorderWindow <= 0 && c.ProcessType == "parallel-pipe" {
+		c.ReorderWindow = c.Workers * 2
+	}
+	
+	return c
+}
+
+// ffmpegVersion returns the first line of ffmpeg -version, or "unknown"
+func (v *Visualizer) ffmpegVersion() string {
+	output, err := exec.Command(v.ffmpegPath(), "-version").Output()
+	if err != nil {
+		return "unknown"
+	}
+	line, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(line)
+}
+
+// fileSHA256 returns the hex SHA-256 digest of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -2,8 +2,16 @@ package audiospectrum
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/fogleman/gg"
 )
 
 // Config holds all configuration options for the audio spectrum visualizer
@@ -11,6 +19,7 @@ type Config struct {
 	InputFile    string
 	OutputFile   string
 	FPS          int
+	StartTime    float64
 	Duration     float64
 	BarCount     int
 	ColorScheme  ColorScheme
@@ -19,6 +28,242 @@ type Config struct {
 	Width        int
 	Height       int
 	ProcessType  ProcessType
+
+	// Workers caps the goroutines the parallel methods render with, leaving
+	// CPU for other jobs (0 = one per CPU)
+	Workers int
+
+	// ReorderWindow caps how many frames parallel-pipe mode keeps in memory
+	// while waiting to write them in order (0 = twice the worker count)
+	ReorderWindow int
+
+	// FrameLimit is the most frames a PNG-based render may produce before it
+	// is refused in favour of a pipe mode (0 disables the check)
+	FrameLimit int
+
+	// FrameFormat is the image format the fast and parallel methods write
+	// their temporary frames in; JPEG at FrameQuality (1-100, zero means 90)
+	// trades some quality for far less disk
+	FrameFormat  FrameFormat
+	FrameQuality int
+
+	// Segments selects time windows of the input that are stitched together,
+	// in order, for both the analysis and the output audio. When set it
+	// replaces Duration.
+	Segments []Segment
+
+	// LoopToDuration repeats the audio and its visualization until the video
+	// is this many seconds long (0 = no looping). It must be at least as long
+	// as the source audio.
+	LoopToDuration float64
+
+	// PadToDuration extends the video to Duration with silence and flat bars
+	// when the audio (after StartTime) is shorter. Without it the video ends
+	// with the audio and a warning is printed.
+	PadToDuration bool
+
+	// External tools
+	FFmpegPath  string
+	FFprobePath string
+
+	// Analysis options
+	SampleRate     int // Analysis rate: 22050, 44100 or 48000; higher rates show more treble
+	SampleFormat   SampleFormat
+	HopLength      int // Samples between analysis windows, 0 = one window per video frame
+	AmplitudeScale AmplitudeScale
+	DBFloor        float64
+	Smoothing      float64 // 0 = none, 1 = maximum; higher values make bars more sticky
+	Sensitivity    float64 // Multiplier on bar heights, 1 = unchanged, 2 = roughly twice as responsive
+	NoiseGate      float64 // Bars below this 0-1 level are drawn flat; 0 disables the gate
+	BinAggregation BinAggregation
+	FreqScale      FreqScale // Spacing of bar frequency ranges: log, linear or mel
+	Weighting      Weighting // Loudness curve applied to FFT bins: none or a-weight
+	FractionalBins bool // Weight FFT bins by how much of each a bar's range covers
+
+	// LogScaleMultiplier and LogScaleDivisor shape the log amplitude scale,
+	// log10(level*multiplier+1)/divisor: a larger multiplier lifts quiet
+	// levels and a smaller divisor makes every bar taller. Zero means the
+	// defaults of 1000 and 3, where a full-scale level just reaches the top.
+	LogScaleMultiplier float64
+	LogScaleDivisor    float64
+
+	// Normalize rescales the whole track's bar levels after analysis so its
+	// loudest moment just reaches full height: quiet recordings use the full
+	// range and loud ones stop clipping. NoiseGate then applies to the
+	// rescaled levels.
+	Normalize bool
+
+	// AGC applies automatic gain instead: each frame is rescaled against the
+	// loudest level of the last AGCWindow seconds (zero means 3), so quiet and
+	// loud sections both keep lively bars. Cannot be combined with Normalize.
+	AGC       bool
+	AGCWindow float64
+
+	SurroundDownmix bool // Fold 5.x/6.1/7.1 input to mono with center/surround weighting, dropping LFE
+
+	// Style options
+	BarGap    float64 // Fraction of each bar's slot left as space, 0-1
+	BarBevel  bool
+	PeakHold  bool
+	PeakDecay float64 // Amount a peak cap falls per frame
+
+	BarGradient bool // Fill bars (and the area type) from their silent color at the base to their level's color at the top
+
+	BarCornerRadius float64 // Pixels; clamped to half the bar width and height
+	MinBarHeight    float64 // Pixels every bar keeps in silence, a flat baseline; 0 lets silent bars vanish
+
+	// SilenceThreshold is the level below which every visualization draws a
+	// band as silent: at its resting size in the scheme's silent color
+	SilenceThreshold float64
+
+	// HeightCurve maps band levels to the lengths of bars, mirror bars, hbars
+	// and the circular and radial spokes; colors still follow the level itself
+	HeightCurve HeightCurve
+
+	Reflection        bool    // Draw a fading mirror image of the bars below them, raising the baseline
+	ReflectionOpacity float64 // Opacity of the reflection where it meets the bars, 0-1
+
+	Orientation  Orientation // Edge the bars visualization grows from
+	ShowFreqAxis bool        // Label band frequencies along that edge of the bars visualization
+	Symmetric    bool        // Put the lowest band at the center of the bars and mirror to both edges
+
+	SegmentedBars bool
+	SegmentCount  int
+
+	SmoothLine bool
+	LineCap    LineCap // Ends of stroked lines in the line, wave, circular and spiral types
+
+	// Interpolation upsamples the bands of the line, wave and area types to
+	// smooth them at low bar counts; other types draw one shape per band
+	Interpolation Interpolation
+
+	SpiralTurns  float64 // Turns the spiral visualization makes, up to 20
+	DotsMaxCount int     // Dots a full-level band adds above its base dot in the dots visualization, up to 100
+	DotSize      float64 // Dot radius multiplier in the dots visualization, up to 10
+
+	// RotationSpeed spins the circular, radial and spiral visualizations by
+	// this many degrees per second of video (negative values spin the other
+	// way, 0 keeps them still)
+	RotationSpeed float64
+
+	// VisualSpeed scales how fast the visuals move through the spectrum while
+	// the audio plays normally: 0.5 is half-speed slow motion, 2 double speed.
+	// Visuals drift out of sync by (1-VisualSpeed) seconds per second of video,
+	// and above 1 wrap around to the start once they reach the end.
+	VisualSpeed float64
+
+	ColorMode ColorMode
+	HueSpan   float64 // Degrees of the color wheel spread across the bars in frequency mode
+	BaseHue   float64 // Hue in degrees of the lowest band in frequency mode
+
+	// ColorCycleSpeed rotates the hue of the bar colors by this many degrees
+	// per second of video, so the palette slowly evolves (negative values
+	// rotate the other way, 0 disables). Accent colors are left as set.
+	ColorCycleSpeed float64
+
+	AccentColor     string  // Hex color such as "#ff0000"; empty disables the accent
+	AccentThreshold float64 // Magnitudes above this use AccentColor instead of the scheme
+
+	// EQBands gives frequency ranges fixed colors, e.g. bass red, mids green
+	// and highs blue. Bars outside every band keep the scheme's colors; where
+	// bands overlap the first one listed wins.
+	EQBands []EQBand
+
+	GlowThreshold float64 // Magnitudes above this get a glow around them
+	GlowOpacity   float64 // Glow opacity, 0-1; 0 disables the glow
+	GlowColor     string  // Hex color such as "#ffffff", or "scheme" to glow in each bar's own color
+
+	// Drop shadow (or, in a light color with no offset, an outer glow) behind
+	// the whole visualization
+	Shadow       bool
+	ShadowColor  string  // Hex color such as "#000000"
+	ShadowOffset float64 // Pixels the shadow is shifted right and down; negative shifts up and left
+	ShadowBlur   float64 // Blur radius in pixels; 0 gives a hard-edged shadow
+
+	// Background options
+	BackgroundImage string
+	BackgroundFit   BackgroundFit
+	BackgroundBlur  float64 // Blur radius in pixels so the image doesn't compete with the bars
+	AutoBackground  bool    // Use the input's embedded cover art, blurred, when BackgroundImage is empty
+
+	// Vertical gradient background from top to bottom hex color; both must be
+	// set, and it replaces BGColor (which also accepts a hex color like "#101820")
+	BGGradientTop    string
+	BGGradientBottom string
+
+	// Watermark options
+	WatermarkFile     string   // PNG/JPEG logo drawn on every frame; larger logos are scaled down
+	WatermarkPosition Position // Corner or center of the frame
+	WatermarkOpacity  float64  // 0-1
+
+	// Title options
+	TitleText     string
+	TitleFont     string   // TTF file; empty uses gg's built-in basic font
+	TitleSize     float64  // Font size in points
+	TitleColor    string   // Hex color such as "#ffffff"
+	TitlePosition Position // Corner or center of the frame
+
+	// Center options for the circular and circular-wave visualizations
+	CenterImage  string  // PNG/JPEG clipped to a circle in the middle, e.g. album art
+	CenterRadius float64 // Pixels; 0 keeps the default 80
+	CenterPulse  float64 // Extra size at full bass, 0.2 = 20% larger; 0 keeps it still
+
+	// Output options
+	OutputFormat OutputFormat // Container; empty picks it from the OutputFile extension
+	LoopCount    int          // GIF/WebP plays: 0 = loop forever, 1 = play once, N = play N times
+	VideoCodec   string
+	VideoCRF     int // 0 leaves the encoder default
+	VideoPreset  string
+	AudioBitrate string
+	HWAccel      HWAccel // Falls back to libx264 when the encoder is unavailable
+
+	// Delivery specs: the chroma layout and bit depth, and the H.264 profile
+	// and level (empty lets the encoder pick them)
+	PixelFormat string // yuv420p, yuv422p, yuv444p, yuv420p10le or yuv422p10le
+	Profile     string // baseline, main, high, high10, high422 or high444
+	Level       string // 3.0 to 5.2, such as "4.1"
+
+	// NoOverwrite fails the render up front instead of replacing an existing
+	// OutputFile. AutoIncrement writes to the first free name with _1, _2, ...
+	// added before the extension, so nothing is ever replaced.
+	NoOverwrite   bool
+	AutoIncrement bool
+
+	// CacheDir keeps rendered PNG frames keyed by a hash of the visual
+	// settings and input, so re-encoding with new output options skips
+	// rendering and an interrupted render resumes from the frames it had
+	// written. Frames are stored by the fast and parallel process types;
+	// any process type reuses a complete entry.
+	CacheDir string
+
+	// WriteManifest is a path for a JSON sidecar recording the effective
+	// config, input hash, ffmpeg version and timings of a successful render
+	WriteManifest string
+
+	// ExportData is a path to write the 0-1 level of every bar at every video
+	// frame to, as drawn after SilenceThreshold, with timestamps and band
+	// frequencies, for syncing lights or other visuals: CSV when it ends in
+	// .csv, JSON otherwise
+	ExportData string
+
+	// OverlayOnInput renders transparent frames and composites them,
+	// centered along the bottom edge, onto the video stream of InputFile,
+	// keeping its original audio. Background options are ignored.
+	OverlayOnInput bool
+}
+
+// EQBand colors the bars whose center frequency falls in [MinFreq, MaxFreq)
+// Hz with Color, a hex color such as "#ff0000", whatever their level
+type EQBand struct {
+	MinFreq float64
+	MaxFreq float64
+	Color   string
+}
+
+// Segment is a time window of the input audio, in seconds
+type Segment struct {
+	Start    float64
+	Duration float64
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -34,64 +279,420 @@ func DefaultConfig() *Config {
 		Width:        1280,
 		Height:       720,
 		ProcessType:  ProcessTypeFast,
+
+		FrameLimit: 108000, // One hour at 30 FPS
+
+		FrameFormat: FrameFormatPNG,
+
+		FFmpegPath:  "ffmpeg",
+		FFprobePath: "ffprobe",
+
+		SampleRate:     22050,
+		SampleFormat:   SampleFormatF32,
+		AmplitudeScale: AmplitudeScaleLog,
+		DBFloor:        -60,
+		Smoothing:      0.15,
+		Sensitivity:    1,
+		AGCWindow:      3,
+		BinAggregation: BinAggregationAverage,
+		FreqScale:      FreqScaleLog,
+		Weighting:      WeightingNone,
+
+		LogScaleMultiplier: 1000,
+		LogScaleDivisor:    3,
+
+		SurroundDownmix: true,
+
+		BarGap:    0.2,
+		PeakDecay: 0.02,
+
+		MinBarHeight:     5,
+		SilenceThreshold: 0.01,
+		HeightCurve:      HeightCurveLinear,
+
+		ReflectionOpacity: 0.4,
+
+		Orientation: OrientationBottomUp,
+
+		SegmentCount: 16,
+
+		LineCap:       LineCapRound,
+		Interpolation: InterpolationNone,
+
+		SpiralTurns:  2,
+		DotsMaxCount: 10,
+		DotSize:      1,
+
+		VisualSpeed: 1,
+
+		ColorMode: ColorModeMagnitude,
+		HueSpan:   360,
+
+		AccentThreshold: 0.8,
+
+		GlowThreshold: 0.5,
+		GlowOpacity:   0.3,
+		GlowColor:     "#ffffff",
+
+		ShadowColor:  "#000000",
+		ShadowOffset: 6,
+		ShadowBlur:   8,
+
+		BackgroundFit: BackgroundFitCover,
+
+		WatermarkPosition: PositionBottomRight,
+		WatermarkOpacity:  1,
+
+		TitleSize:     36,
+		TitleColor:    "#ffffff",
+		TitlePosition: PositionTopLeft,
+
+		VideoCodec:   "libx264",
+		VideoCRF:     23,
+		VideoPreset:  "ultrafast",
+		AudioBitrate: "192k",
+		HWAccel:      HWAccelNone,
+
+		PixelFormat: "yuv420p",
 	}
 }
 
+// GenerateResult describes a finished render
+type GenerateResult struct {
+	OutputFile string
+	FrameCount int
+	Duration   float64       // Seconds of audio rendered
+	RenderTime time.Duration // Wall time spent rendering and encoding
+	FileSize   int64         // Output size in bytes
+	Width      int
+	Height     int
+	FPS        int
+}
+
 // Generate creates an audio spectrum video from the given audio file
 func Generate(config *Config) error {
-	// Validate input
-	if config.InputFile == "" {
-		return fmt.Errorf("input file is required")
+	_, err := GenerateWithResult(config)
+	return err
+}
+
+// GenerateWithResult creates an audio spectrum video like Generate and
+// returns metadata about the finished render
+func GenerateWithResult(config *Config) (*GenerateResult, error) {
+	if err := checkDependencies(config.FFmpegPath, config.FFprobePath); err != nil {
+		return nil, err
 	}
 	
-	if _, err := os.Stat(config.InputFile); os.IsNotExist(err) {
-		return fmt.Errorf("input file not found: %s", config.InputFile)
+	if err := checkConfig(config); err != nil {
+		return nil, err
 	}
 	
-	// Validate configuration
-	if err := validateConfig(config); err != nil {
-		return fmt.Errorf("invalid configuration: %w", err)
+	// Create and run visualizer
+	return renderVideo(config, NewVisualizer(newVisualizerConfig(config)))
+}
+
+// renderVideo runs a checked config's visualizer, reading a file or the
+// stream set as its inputReader, writes the manifest and level data if
+// requested and reports the finished output
+func renderVideo(config *Config, visualizer *Visualizer) (*GenerateResult, error) {
+	if visualizer.inputReader != nil {
+		fmt.Println("Processing audio stream")
+	} else {
+		fmt.Printf("Processing audio file: %s\n", config.InputFile)
 	}
+	startTime := time.Now()
 	
-	// Create visualizer config
-	vizConfig := &VisualizerConfig{
-		InputFile:    config.InputFile,
-		OutputFile:   config.OutputFile,
-		FPS:          config.FPS,
-		Duration:     config.Duration,
-		BarCount:     config.BarCount,
-		ColorScheme:  string(config.ColorScheme),
-		VizType:      string(config.VisType),
-		BgColor:      string(config.BGColor),
-		Width:        config.Width,
-		Height:       config.Height,
-		ProcessType:  string(config.ProcessType),
+	if err := visualizer.CreateVideo(); err != nil {
+		return nil, fmt.Errorf("failed to generate video: %w", err)
 	}
 	
-	// Create and run visualizer
-	visualizer := NewVisualizer(vizConfig)
+	// Get file size of the output, renamed if AutoIncrement avoided a clash
+	outputFile := visualizer.output()
+	fileInfo, err := os.Stat(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output file info: %w", err)
+	}
 	
-	fmt.Printf("Processing audio file: %s\n", config.InputFile)
+	duration := time.Since(startTime)
+	fmt.Printf("\nVideo created successfully: %s\n", outputFile)
+	fmt.Printf("Total processing time: %.1f seconds\n", duration.Seconds())
+	fmt.Printf("Output file size: %.1f MB\n", float64(fileInfo.Size())/(1024*1024))
+	
+	if config.WriteManifest != "" {
+		if err := visualizer.writeManifest(config.WriteManifest, startTime, startTime.Add(duration)); err != nil {
+			return nil, fmt.Errorf("failed to write manifest: %w", err)
+		}
+		fmt.Printf("Render manifest: %s\n", config.WriteManifest)
+	}
+	if err := visualizer.exportLevelData(config.ExportData); err != nil {
+		return nil, err
+	}
+	
+	return &GenerateResult{
+		OutputFile: outputFile,
+		FrameCount: visualizer.totalFrames,
+		Duration:   visualizer.duration,
+		RenderTime: duration,
+		FileSize:   fileInfo.Size(),
+		Width:      config.Width,
+		Height:     config.Height,
+		FPS:        config.FPS,
+	}, nil
+}
+
+// GenerateFromReader creates an audio spectrum video from audio read from r,
+// such as an HTTP response body, by piping it into ffmpeg's stdin.
+// config.InputFile is ignored. The stream isn't probed, so the video covers
+// the whole decoded audio, or config.Duration seconds when set. Formats that
+// need seeking, like MP4 with its index at the end, can't be streamed.
+func GenerateFromReader(r io.Reader, config *Config) error {
+	if r == nil {
+		return fmt.Errorf("reader is required")
+	}
+	
+	if err := checkDependencies(config.FFmpegPath, config.FFprobePath); err != nil {
+		return err
+	}
+	
+	if err := checkReaderConfig(config); err != nil {
+		return err
+	}
+	
+	// Muxing needs the audio again after analysis has consumed the stream
+	tempDir, err := os.MkdirTemp("", "spectrum_stream_*")
+	if err != nil {
+		return fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	
+	visualizer := NewVisualizer(newVisualizerConfig(config))
+	visualizer.inputReader = r
+	visualizer.streamCopy = filepath.Join(tempDir, "audio.mka")
+	
+	_, err = renderVideo(config, visualizer)
+	return err
+}
+
+// GenerateTo creates an audio spectrum video like Generate but writes the
+// encoded video to w, such as an http.ResponseWriter, instead of a file.
+// config.OutputFile is ignored except to pick the format when OutputFormat
+// is empty (MP4 if neither says). MP4 is written fragmented, since a regular
+// MP4 needs its index rewritten after encoding, which a stream can't do.
+func GenerateTo(w io.Writer, config *Config) error {
+	if w == nil {
+		return fmt.Errorf("writer is required")
+	}
+	
+	if err := checkDependencies(config.FFmpegPath, config.FFprobePath); err != nil {
+		return err
+	}
+	
+	// Encode to ffmpeg's stdout in the format the output file would have had
+	c := *config
+	c.OutputFormat = OutputFormat(orDefault(resolveOutputFormat(string(config.OutputFormat), config.OutputFile), "mp4"))
+	c.OutputFile = pipeOutput
+	if err := checkConfig(&c); err != nil {
+		return err
+	}
+	if c.WriteManifest != "" {
+		return invalidConfig(invalidField("WriteManifest", c.WriteManifest, "render manifests are not supported when writing to a stream"))
+	}
+	
+	visualizer := NewVisualizer(newVisualizerConfig(&c))
+	counter := &countingWriter{w: w}
+	visualizer.outputWriter = counter
+	
+	fmt.Printf("Processing audio file: %s\n", c.InputFile)
 	startTime := time.Now()
 	
 	if err := visualizer.CreateVideo(); err != nil {
 		return fmt.Errorf("failed to generate video: %w", err)
 	}
 	
-	// Get file size
-	fileInfo, err := os.Stat(config.OutputFile)
+	fmt.Printf("\nVideo streamed successfully as %s\n", c.OutputFormat)
+	fmt.Printf("Total processing time: %.1f seconds\n", time.Since(startTime).Seconds())
+	fmt.Printf("Output size: %.1f MB\n", float64(counter.n)/(1024*1024))
+	
+	if err := visualizer.exportLevelData(c.ExportData); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// countingWriter passes writes through to w and counts the bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// GenerateFrames renders the spectrum as a PNG sequence named
+// frame_%06d.png in outDir instead of a video, skipping the ffmpeg encode.
+// OutputFile is ignored, and outDir is not removed afterwards. With
+// OverlayOnInput the frames are transparent, ready for compositing.
+// WriteManifest describes a video, so it is rejected.
+func GenerateFrames(config *Config, outDir string) error {
+	if err := checkDependencies(config.FFmpegPath, config.FFprobePath); err != nil {
+		return err
+	}
+	
+	if err := checkConfig(config); err != nil {
+		return err
+	}
+	if config.WriteManifest != "" {
+		return invalidConfig(invalidField("WriteManifest", config.WriteManifest, "render manifests are not supported when writing frames"))
+	}
+	
+	if outDir == "" {
+		return fmt.Errorf("output directory is required")
+	}
+	
+	visualizer := NewVisualizer(newVisualizerConfig(config))
+	
+	fmt.Printf("Processing audio file: %s\n", config.InputFile)
+	startTime := time.Now()
+	
+	if err := visualizer.CreateFrames(outDir); err != nil {
+		return fmt.Errorf("failed to generate frames: %w", err)
+	}
+	
+	fmt.Printf("\nFrames written to: %s\n", outDir)
+	fmt.Printf("Total processing time: %.1f seconds\n", time.Since(startTime).Seconds())
+	
+	if err := visualizer.exportLevelData(config.ExportData); err != nil {
+		return err
+	}
+	
+	return nil
+}
+
+// GenerateThumbnails renders the frame at atSeconds and saves it as a PNG
+// scaled to each of the given width/height pairs in outDir
+func GenerateThumbnails(config *Config, atSeconds float64, sizes [][2]int, outDir string) error {
+	if err := checkDependencies(config.FFmpegPath, config.FFprobePath); err != nil {
+		return err
+	}
+	
+	if err := checkConfig(config); err != nil {
+		return err
+	}
+	
+	if atSeconds < 0 {
+		return fmt.Errorf("thumbnail time cannot be negative")
+	}
+	if len(sizes) == 0 {
+		return fmt.Errorf("at least one thumbnail size is required")
+	}
+	for _, size := range sizes {
+		if size[0] < 1 || size[1] < 1 {
+			return fmt.Errorf("invalid thumbnail size: %dx%d", size[0], size[1])
+		}
+	}
+	
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("creating output dir: %w", err)
+	}
+	
+	visualizer := NewVisualizer(newVisualizerConfig(config))
+	img, err := visualizer.RenderStill(atSeconds)
 	if err != nil {
-		return fmt.Errorf("failed to get output file info: %w", err)
+		return fmt.Errorf("failed to render still: %w", err)
 	}
 	
-	duration := time.Since(startTime)
-	fmt.Printf("\nVideo created successfully: %s\n", config.OutputFile)
-	fmt.Printf("Total processing time: %.1f seconds\n", duration.Seconds())
-	fmt.Printf("Output file size: %.1f MB\n", float64(fileInfo.Size())/(1024*1024))
+	for _, size := range sizes {
+		filename := filepath.Join(outDir, fmt.Sprintf("thumb_%dx%d.png", size[0], size[1]))
+		if err := gg.SavePNG(filename, scaleImage(img, size[0], size[1])); err != nil {
+			return fmt.Errorf("saving thumbnail %s: %w", filename, err)
+		}
+	}
 	
 	return nil
 }
 
+// ComputeSpectrum decodes and analyses the input as Generate would and returns
+// the bar levels, 0-1, of every frame (frames x BarCount) without rendering
+// anything. There is one row per frame at FPS, with all analysis options
+// applied; with LoopToDuration the rows cover the source audio once.
+func ComputeSpectrum(config *Config) ([][]float64, error) {
+	if err := checkConfig(config); err != nil {
+		return nil, err
+	}
+	
+	// WAV files read natively don't need ffmpeg at all
+	visualizer := NewVisualizer(newVisualizerConfig(config))
+	if visualizer.nativeWAV() == nil || config.AutoBackground {
+		if err := checkDependencies(config.FFmpegPath, config.FFprobePath); err != nil {
+			return nil, err
+		}
+	}
+	
+	if err := visualizer.loadAudio(); err != nil {
+		return nil, fmt.Errorf("failed to load audio: %w", err)
+	}
+	if err := visualizer.precomputeSpectrum(); err != nil {
+		return nil, fmt.Errorf("failed to compute spectrum: %w", err)
+	}
+	
+	return visualizer.spectrumData, nil
+}
+
+// ResourceEstimate describes the memory and disk a render is expected to need
+type ResourceEstimate struct {
+	Duration       float64 // Seconds of audio that will be rendered
+	FrameCount     int
+	AudioMemory    int64 // Bytes held by the decoded audio samples
+	SpectrumMemory int64 // Bytes held by the precomputed spectrum data
+	FrameMemory    int64 // Bytes per rendered frame in memory
+	TempDiskUsage  int64 // Approximate bytes of temporary PNG frames (0 for pipe modes)
+}
+
+// RenderEstimate summarizes a render before it runs: the resource estimate,
+// the frame size, and a rough time to draw the frames
+type RenderEstimate struct {
+	ResourceEstimate
+	Width      int
+	Height     int
+	RenderTime time.Duration // Rough wall-clock time to draw and hand off every frame
+}
+
+// EstimateResources probes the input duration and estimates the memory and
+// temporary disk a render with this configuration would use, without
+// decoding the audio or rendering anything
+func EstimateResources(config *Config) (*ResourceEstimate, error) {
+	if err := checkConfig(config); err != nil {
+		return nil, err
+	}
+	
+	visualizer := NewVisualizer(newVisualizerConfig(config))
+	if err := visualizer.probeAudio(); err != nil {
+		return nil, fmt.Errorf("failed to probe audio: %w", err)
+	}
+	
+	return visualizer.estimateResources(), nil
+}
+
+// EstimateRender is a dry run of a render: it probes the input duration like
+// EstimateResources and adds the frame size and a rough render time scaled
+// by pixels per frame, frame count and worker count. Nothing is decoded,
+// analysed or drawn, so it is cheap enough to reject absurd jobs up front.
+func EstimateRender(config *Config) (*RenderEstimate, error) {
+	if err := checkConfig(config); err != nil {
+		return nil, err
+	}
+	
+	visualizer := NewVisualizer(newVisualizerConfig(config))
+	if err := visualizer.probeAudio(); err != nil {
+		return nil, fmt.Errorf("failed to probe audio: %w", err)
+	}
+	
+	return visualizer.estimateRender(), nil
+}
+
 // GenerateWithDefaults creates a video with default settings, only requiring input/output files
 func GenerateWithDefaults(inputFile, outputFile string) error {
 	config := DefaultConfig()
@@ -100,53 +701,756 @@ func GenerateWithDefaults(inputFile, outputFile string) error {
 	return Generate(config)
 }
 
+// CheckDependencies verifies that ffmpeg and ffprobe are installed and on PATH
+func CheckDependencies() error {
+	return checkDependencies("ffmpeg", "ffprobe")
+}
+
+// CheckDependenciesFor verifies that the ffmpeg and ffprobe binaries config
+// names in FFmpegPath and FFprobePath can be run, defaulting to PATH
+func CheckDependenciesFor(config *Config) error {
+	return checkDependencies(config.FFmpegPath, config.FFprobePath)
+}
+
+// checkDependencies runs each tool with -version and returns an actionable
+// error naming the first one that cannot be executed
+func checkDependencies(ffmpegPath, ffprobePath string) error {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	if ffprobePath == "" {
+		ffprobePath = "ffprobe"
+	}
+	
+	for _, tool := range []struct{ name, path, field string }{
+		{"ffmpeg", ffmpegPath, "FFmpegPath"},
+		{"ffprobe", ffprobePath, "FFprobePath"},
+	} {
+		if err := exec.Command(tool.path, "-version").Run(); err != nil {
+			return fmt.Errorf("%s is not available (tried %q): %w; install FFmpeg "+
+				"(macOS: brew install ffmpeg, Ubuntu/Debian: sudo apt-get install ffmpeg, "+
+				"Windows: https://ffmpeg.org/download.html) or set Config.%s",
+				tool.name, tool.path, err, tool.field)
+		}
+	}
+	
+	return nil
+}
+
+// checkConfig verifies the input file exists and the configuration is valid
+func checkConfig(config *Config) error {
+	// Validate input
+	if config.InputFile == "" {
+		return invalidConfig(invalidField("InputFile", config.InputFile, "input file is required"))
+	}
+	
+	if _, err := os.Stat(config.InputFile); os.IsNotExist(err) {
+		return invalidConfig(invalidField("InputFile", config.InputFile, "input file not found: %s", config.InputFile))
+	}
+	
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return invalidConfig(err)
+	}
+	
+	return nil
+}
+
+// checkReaderConfig validates the configuration for GenerateFromReader and
+// rejects the options that need to probe, seek or re-read an input file
+func checkReaderConfig(config *Config) error {
+	if err := config.Validate(); err != nil {
+		return invalidConfig(err)
+	}
+	
+	switch {
+	case len(config.Segments) > 0:
+		return invalidConfig(invalidField("Segments", config.Segments, "segments are not supported when reading from a stream"))
+	case config.OverlayOnInput:
+		return invalidConfig(invalidField("OverlayOnInput", config.OverlayOnInput, "overlay on input is not supported when reading from a stream"))
+	case config.AutoBackground:
+		return invalidConfig(invalidField("AutoBackground", config.AutoBackground, "auto background is not supported when reading from a stream"))
+	case config.CacheDir != "":
+		return invalidConfig(invalidField("CacheDir", config.CacheDir, "frame caching is not supported when reading from a stream"))
+	case config.WriteManifest != "":
+		return invalidConfig(invalidField("WriteManifest", config.WriteManifest, "render manifests are not supported when reading from a stream"))
+	}
+	
+	return nil
+}
+
+// newVisualizerConfig converts a public Config into a VisualizerConfig
+func newVisualizerConfig(config *Config) *VisualizerConfig {
+	return &VisualizerConfig{
+		InputFile:    config.InputFile,
+		OutputFile:   config.OutputFile,
+		FPS:          config.FPS,
+		StartTime:    config.StartTime,
+		Duration:     config.Duration,
+		BarCount:     config.BarCount,
+		ColorScheme:  string(config.ColorScheme),
+		VizType:      string(config.VisType),
+		BgColor:      string(config.BGColor),
+		Width:        config.Width,
+		Height:       config.Height,
+		ProcessType:  string(config.ProcessType),
+
+		Workers:       config.Workers,
+		ReorderWindow: config.ReorderWindow,
+		FrameLimit:    config.FrameLimit,
+		FrameFormat:   config.FrameFormat,
+		FrameQuality:  config.FrameQuality,
+
+		FFmpegPath:  config.FFmpegPath,
+		FFprobePath: config.FFprobePath,
+
+		SampleRate:     config.SampleRate,
+		SampleFormat:   string(config.SampleFormat),
+		HopLength:      config.HopLength,
+		AmplitudeScale: string(config.AmplitudeScale),
+		DBFloor:        config.DBFloor,
+		Smoothing:      config.Smoothing,
+		Sensitivity:    config.Sensitivity,
+		NoiseGate:      config.NoiseGate,
+		BinAggregation: string(config.BinAggregation),
+		FreqScale:      string(config.FreqScale),
+		Weighting:      string(config.Weighting),
+		FractionalBins: config.FractionalBins,
+
+		LogScaleMultiplier: config.LogScaleMultiplier,
+		LogScaleDivisor:    config.LogScaleDivisor,
+
+		Normalize: config.Normalize,
+		AGC:       config.AGC,
+		AGCWindow: config.AGCWindow,
+
+		SurroundDownmix: config.SurroundDownmix,
+
+		BarGap:    config.BarGap,
+		BarBevel:  config.BarBevel,
+		PeakHold:  config.PeakHold,
+		PeakDecay: config.PeakDecay,
+
+		BarGradient: config.BarGradient,
+
+		BarCornerRadius: config.BarCornerRadius,
+		MinBarHeight:    config.MinBarHeight,
+
+		SilenceThreshold: config.SilenceThreshold,
+		HeightCurve:      string(config.HeightCurve),
+
+		Reflection:        config.Reflection,
+		ReflectionOpacity: config.ReflectionOpacity,
+
+		Orientation:  string(config.Orientation),
+		ShowFreqAxis: config.ShowFreqAxis,
+		Symmetric:    config.Symmetric,
+
+		SegmentedBars: config.SegmentedBars,
+		SegmentCount:  config.SegmentCount,
+
+		SmoothLine: config.SmoothLine,
+		LineCap:    string(config.LineCap),
+
+		Interpolation: string(config.Interpolation),
+
+		SpiralTurns:  config.SpiralTurns,
+		DotsMaxCount: config.DotsMaxCount,
+		DotSize:      config.DotSize,
+
+		RotationSpeed: config.RotationSpeed,
+
+		VisualSpeed: config.VisualSpeed,
+
+		ColorMode: string(config.ColorMode),
+		HueSpan:   config.HueSpan,
+		BaseHue:   config.BaseHue,
+
+		ColorCycleSpeed: config.ColorCycleSpeed,
+
+		AccentColor:     config.AccentColor,
+		AccentThreshold: config.AccentThreshold,
+
+		EQBands: config.EQBands,
+
+		GlowThreshold: config.GlowThreshold,
+		GlowOpacity:   config.GlowOpacity,
+		GlowColor:     config.GlowColor,
+
+		Shadow:       config.Shadow,
+		ShadowColor:  config.ShadowColor,
+		ShadowOffset: config.ShadowOffset,
+		ShadowBlur:   config.ShadowBlur,
+
+		BackgroundImage: config.BackgroundImage,
+		BackgroundFit:   string(config.BackgroundFit),
+		BackgroundBlur:  config.BackgroundBlur,
+		AutoBackground:  config.AutoBackground,
+
+		BGGradientTop:    config.BGGradientTop,
+		BGGradientBottom: config.BGGradientBottom,
+
+		WatermarkFile:     config.WatermarkFile,
+		WatermarkPosition: string(config.WatermarkPosition),
+		WatermarkOpacity:  config.WatermarkOpacity,
+
+		TitleText:     config.TitleText,
+		TitleFont:     config.TitleFont,
+		TitleSize:     config.TitleSize,
+		TitleColor:    config.TitleColor,
+		TitlePosition: string(config.TitlePosition),
+
+		CenterImage:  config.CenterImage,
+		CenterRadius: config.CenterRadius,
+		CenterPulse:  config.CenterPulse,
+
+		OutputFormat: string(config.OutputFormat),
+		LoopCount:    config.LoopCount,
+		VideoCodec:   config.VideoCodec,
+		VideoCRF:     config.VideoCRF,
+		VideoPreset:  config.VideoPreset,
+		AudioBitrate: config.AudioBitrate,
+		HWAccel:      string(config.HWAccel),
+
+		PixelFormat: config.PixelFormat,
+		Profile:     config.Profile,
+		Level:       config.Level,
+
+		NoOverwrite:   config.NoOverwrite,
+		AutoIncrement: config.AutoIncrement,
+
+		OverlayOnInput: config.OverlayOnInput,
+
+		CacheDir: config.CacheDir,
+
+		Segments: config.Segments,
+		
+		LoopToDuration: config.LoopToDuration,
+		PadToDuration:  config.PadToDuration,
+	}
+}
+
+// Validate checks the configuration's options without touching the input or
+// doing any rendering work, returning the first problem found. Generate and
+// the other entry points run the same checks before rendering, so a config
+// that passes here only fails later on file or ffmpeg errors.
+func (c *Config) Validate() error {
+	return validateConfig(c)
+}
+
 func validateConfig(config *Config) error {
 	// Validate FPS
 	if config.FPS < 1 || config.FPS > 120 {
-		return fmt.Errorf("FPS must be between 1 and 120")
+		return invalidField("FPS", config.FPS, "FPS must be between 1 and 120")
 	}
 	
 	// Validate duration
 	if config.Duration < 0 {
-		return fmt.Errorf("duration cannot be negative")
+		return invalidField("Duration", config.Duration, "duration cannot be negative")
+	}
+	
+	// Validate start time (checked against the file's length once probed)
+	if config.StartTime < 0 {
+		return invalidField("StartTime", config.StartTime, "start time cannot be negative")
+	}
+	if config.StartTime > 0 && len(config.Segments) > 0 {
+		return invalidField("StartTime", config.StartTime, "start time cannot be combined with segments, which set their own start times")
+	}
+	
+	// Validate segments
+	for i, seg := range config.Segments {
+		if seg.Start < 0 {
+			return invalidField("Segments", seg, "segment %d start cannot be negative", i)
+		}
+		if seg.Duration <= 0 {
+			return invalidField("Segments", seg, "segment %d duration must be positive", i)
+		}
+	}
+	
+	// Validate loop duration (zero means no looping)
+	if config.LoopToDuration < 0 {
+		return invalidField("LoopToDuration", config.LoopToDuration, "loop duration cannot be negative")
+	}
+	if config.LoopToDuration > 0 && config.OverlayOnInput {
+		return invalidField("LoopToDuration", config.LoopToDuration, "loop duration cannot be combined with overlay on input")
+	}
+	
+	// Validate padding, which only extends a plain Duration
+	if config.PadToDuration {
+		switch {
+		case config.Duration <= 0:
+			return invalidField("PadToDuration", config.PadToDuration, "pad to duration requires a duration")
+		case config.LoopToDuration > 0:
+			return invalidField("PadToDuration", config.PadToDuration, "pad to duration cannot be combined with loop duration")
+		case len(config.Segments) > 0:
+			return invalidField("PadToDuration", config.PadToDuration, "pad to duration cannot be combined with segments")
+		case config.OverlayOnInput:
+			return invalidField("PadToDuration", config.PadToDuration, "pad to duration cannot be combined with overlay on input")
+		}
 	}
 	
 	// Validate bar count
 	if config.BarCount < 8 || config.BarCount > 256 {
-		return fmt.Errorf("bar count must be between 8 and 256")
+		return invalidField("BarCount", config.BarCount, "bar count must be between 8 and 256")
 	}
 	
 	// Validate dimensions
-	if config.Width < 320 || config.Height < 240 {
-		return fmt.Errorf("minimum resolution is 320x240")
+	if config.Width < 320 {
+		return invalidField("Width", config.Width, "minimum resolution is 320x240")
+	}
+	if config.Height < 240 {
+		return invalidField("Height", config.Height, "minimum resolution is 320x240")
+	}
+	if config.Width > 7680 {
+		return invalidField("Width", config.Width, "maximum resolution is 7680x4320")
 	}
-	if config.Width > 7680 || config.Height > 4320 {
-		return fmt.Errorf("maximum resolution is 7680x4320")
+	if config.Height > 4320 {
+		return invalidField("Height", config.Height, "maximum resolution is 7680x4320")
 	}
 	
 	// Validate color scheme
 	if !config.ColorScheme.IsValid() {
-		return fmt.Errorf("invalid color scheme: %s", config.ColorScheme)
+		return invalidField("ColorScheme", config.ColorScheme, "invalid color scheme: %s", config.ColorScheme)
 	}
 	
 	// Validate visualization type
 	if !config.VisType.IsValid() {
-		return fmt.Errorf("invalid visualization type: %s", config.VisType)
+		return invalidField("VisType", config.VisType, "invalid visualization type: %s", config.VisType)
 	}
 	
-	// Validate background color
-	if !config.BGColor.IsValid() {
-		return fmt.Errorf("invalid background color: %s", config.BGColor)
+	// Validate background color (a named color or "#RRGGBB")
+	if strings.HasPrefix(string(config.BGColor), "#") {
+		if _, err := parseHexColor(string(config.BGColor)); err != nil {
+			return invalidField("BGColor", config.BGColor, "invalid background color: %w", err)
+		}
+	} else if !config.BGColor.IsValid() {
+		return invalidField("BGColor", config.BGColor, "invalid background color: %s", config.BGColor)
+	}
+	if config.BGGradientTop != "" || config.BGGradientBottom != "" {
+		if _, err := parseHexColor(config.BGGradientTop); err != nil {
+			return invalidField("BGGradientTop", config.BGGradientTop, "invalid background gradient top color: %w", err)
+		}
+		if _, err := parseHexColor(config.BGGradientBottom); err != nil {
+			return invalidField("BGGradientBottom", config.BGGradientBottom, "invalid background gradient bottom color: %w", err)
+		}
 	}
 	
 	// Validate process type
 	if !config.ProcessType.IsValid() {
-		return fmt.Errorf("invalid process type: %s", config.ProcessType)
+		return invalidField("ProcessType", config.ProcessType, "invalid process type: %s", config.ProcessType)
+	}
+
+	// Validate worker count (zero means one per CPU)
+	if config.Workers < 0 {
+		return invalidField("Workers", config.Workers, "workers cannot be negative")
+	}
+	
+	// Validate reorder window
+	if config.ReorderWindow < 0 {
+		return invalidField("ReorderWindow", config.ReorderWindow, "reorder window cannot be negative")
+	}
+	
+	// Validate frame limit
+	if config.FrameLimit < 0 {
+		return invalidField("FrameLimit", config.FrameLimit, "frame limit cannot be negative")
+	}
+	
+	// Validate frame format (empty means PNG); JPEG has no alpha channel
+	if config.FrameFormat != "" && !config.FrameFormat.IsValid() {
+		return invalidField("FrameFormat", config.FrameFormat, "invalid frame format: %s", config.FrameFormat)
+	}
+	if config.FrameFormat == FrameFormatJPEG && config.OverlayOnInput {
+		return invalidField("FrameFormat", config.FrameFormat, "JPEG frames cannot be used with overlay on input, which needs transparency")
+	}
+	if config.FrameQuality < 0 || config.FrameQuality > 100 {
+		return invalidField("FrameQuality", config.FrameQuality, "frame quality must be between 0 and 100")
+	}
+	
+	// Validate sample rate (zero means the default 22050)
+	switch config.SampleRate {
+	case 0, 22050, 44100, 48000:
+	default:
+		return invalidField("SampleRate", config.SampleRate, "sample rate must be 22050, 44100 or 48000")
+	}
+	
+	// Validate sample format (empty means f32)
+	if config.SampleFormat != "" && !config.SampleFormat.IsValid() {
+		return invalidField("SampleFormat", config.SampleFormat, "invalid sample format: %s", config.SampleFormat)
+	}
+	
+	// Validate hop length (zero means one analysis window per video frame)
+	if config.HopLength < 0 {
+		return invalidField("HopLength", config.HopLength, "hop length cannot be negative")
+	}
+	if config.HopLength > 48000 {
+		return invalidField("HopLength", config.HopLength, "hop length cannot exceed 48000 samples")
+	}
+	
+	// Validate amplitude scale (empty means the default log scale)
+	if config.AmplitudeScale != "" && !config.AmplitudeScale.IsValid() {
+		return invalidField("AmplitudeScale", config.AmplitudeScale, "invalid amplitude scale: %s", config.AmplitudeScale)
+	}
+	if config.DBFloor > 0 {
+		return invalidField("DBFloor", config.DBFloor, "dB floor cannot be positive")
+	}
+	
+	// Validate log scale shape (zero means the defaults)
+	if config.LogScaleMultiplier < 0 {
+		return invalidField("LogScaleMultiplier", config.LogScaleMultiplier, "log scale multiplier cannot be negative")
+	}
+	if config.LogScaleDivisor < 0 {
+		return invalidField("LogScaleDivisor", config.LogScaleDivisor, "log scale divisor cannot be negative")
+	}
+	
+	// Validate smoothing
+	if config.Smoothing < 0 || config.Smoothing > 1 {
+		return invalidField("Smoothing", config.Smoothing, "smoothing must be between 0 and 1")
+	}
+	
+	// Validate sensitivity (zero means the default of 1)
+	if config.Sensitivity < 0 {
+		return invalidField("Sensitivity", config.Sensitivity, "sensitivity must be positive")
+	}
+	
+	// Validate noise gate
+	if config.NoiseGate < 0 || config.NoiseGate >= 1 {
+		return invalidField("NoiseGate", config.NoiseGate, "noise gate must be at least 0 and below 1")
+	}
+	
+	// Validate automatic gain (zero window means the default)
+	if config.AGC && config.Normalize {
+		return invalidField("AGC", config.AGC, "AGC and Normalize cannot be combined")
+	}
+	if config.AGCWindow < 0 || config.AGCWindow > 60 {
+		return invalidField("AGCWindow", config.AGCWindow, "AGC window must be between 0 and 60 seconds")
+	}
+	
+	// Validate bin aggregation (empty means average)
+	if config.BinAggregation != "" && !config.BinAggregation.IsValid() {
+		return invalidField("BinAggregation", config.BinAggregation, "invalid bin aggregation: %s", config.BinAggregation)
+	}
+	
+	// Validate frequency scale (empty means the default log scale)
+	if config.FreqScale != "" && !config.FreqScale.IsValid() {
+		return invalidField("FreqScale", config.FreqScale, "invalid frequency scale: %s", config.FreqScale)
+	}
+	
+	// Validate weighting (empty means no weighting)
+	if config.Weighting != "" && !config.Weighting.IsValid() {
+		return invalidField("Weighting", config.Weighting, "invalid weighting: %s", config.Weighting)
+	}
+	
+	// Validate bar gap
+	if config.BarGap < 0 || config.BarGap >= 1 {
+		return invalidField("BarGap", config.BarGap, "bar gap must be at least 0 and below 1")
+	}
+	
+	// Validate bar corner radius
+	if config.BarCornerRadius < 0 {
+		return invalidField("BarCornerRadius", config.BarCornerRadius, "bar corner radius cannot be negative")
+	}
+	
+	// Validate minimum bar height
+	if config.MinBarHeight < 0 {
+		return invalidField("MinBarHeight", config.MinBarHeight, "minimum bar height cannot be negative")
+	}
+	if config.MinBarHeight >= float64(config.Height) {
+		return invalidField("MinBarHeight", config.MinBarHeight, "minimum bar height must be less than the height")
+	}
+	
+	// Validate silence threshold
+	if config.SilenceThreshold < 0 || config.SilenceThreshold >= 1 {
+		return invalidField("SilenceThreshold", config.SilenceThreshold, "silence threshold must be at least 0 and below 1")
+	}
+	
+	// Validate height curve (empty means linear)
+	if config.HeightCurve != "" && !config.HeightCurve.IsValid() {
+		return invalidField("HeightCurve", config.HeightCurve, "invalid height curve: %s", config.HeightCurve)
+	}
+	
+	// Validate reflection
+	if config.Reflection && (config.ReflectionOpacity <= 0 || config.ReflectionOpacity > 1) {
+		return invalidField("ReflectionOpacity", config.ReflectionOpacity, "reflection opacity must be greater than 0 and at most 1")
+	}
+	
+	// Validate peak decay
+	if config.PeakHold && (config.PeakDecay <= 0 || config.PeakDecay > 1) {
+		return invalidField("PeakDecay", config.PeakDecay, "peak decay must be greater than 0 and at most 1")
+	}
+	
+	// Validate orientation (empty means bottom-up)
+	if config.Orientation != "" && !config.Orientation.IsValid() {
+		return invalidField("Orientation", config.Orientation, "invalid orientation: %s", config.Orientation)
+	}
+	
+	// Validate segment count
+	if config.SegmentedBars && (config.SegmentCount < 2 || config.SegmentCount > 64) {
+		return invalidField("SegmentCount", config.SegmentCount, "segment count must be between 2 and 64")
+	}
+	
+	// Validate line cap (empty means round)
+	if config.LineCap != "" && !config.LineCap.IsValid() {
+		return invalidField("LineCap", config.LineCap, "invalid line cap: %s", config.LineCap)
+	}
+	
+	// Validate interpolation (empty means none)
+	if config.Interpolation != "" && !config.Interpolation.IsValid() {
+		return invalidField("Interpolation", config.Interpolation, "invalid interpolation: %s", config.Interpolation)
+	}
+	
+	// Validate spiral and dots shape (zero means the default)
+	if config.SpiralTurns < 0 || config.SpiralTurns > 20 {
+		return invalidField("SpiralTurns", config.SpiralTurns, "spiral turns must be between 0 and 20")
+	}
+	if config.DotsMaxCount < 0 || config.DotsMaxCount > 100 {
+		return invalidField("DotsMaxCount", config.DotsMaxCount, "dots max count must be between 0 and 100")
+	}
+	if config.DotSize < 0 || config.DotSize > 10 {
+		return invalidField("DotSize", config.DotSize, "dot size must be between 0 and 10")
+	}
+	
+	// Validate rotation speed
+	if config.RotationSpeed < -360 || config.RotationSpeed > 360 {
+		return invalidField("RotationSpeed", config.RotationSpeed, "rotation speed must be between -360 and 360 degrees per second")
+	}
+	
+	// Validate visual speed (0 means normal speed)
+	if config.VisualSpeed < 0 || config.VisualSpeed > 10 {
+		return invalidField("VisualSpeed", config.VisualSpeed, "visual speed must be between 0 and 10")
+	}
+	
+	// Validate color mode (empty means magnitude)
+	if config.ColorMode != "" && !config.ColorMode.IsValid() {
+		return invalidField("ColorMode", config.ColorMode, "invalid color mode: %s", config.ColorMode)
+	}
+	if config.HueSpan < 0 || config.HueSpan > 360 {
+		return invalidField("HueSpan", config.HueSpan, "hue span must be between 0 and 360")
+	}
+	if config.BaseHue < 0 || config.BaseHue >= 360 {
+		return invalidField("BaseHue", config.BaseHue, "base hue must be at least 0 and below 360")
+	}
+	if config.ColorCycleSpeed < -360 || config.ColorCycleSpeed > 360 {
+		return invalidField("ColorCycleSpeed", config.ColorCycleSpeed, "color cycle speed must be between -360 and 360 degrees per second")
+	}
+	
+	// Validate EQ bands
+	for i, band := range config.EQBands {
+		if band.MinFreq < 0 || band.MaxFreq <= band.MinFreq {
+			return invalidField("EQBands", band, "EQ band %d needs a range with 0 <= MinFreq < MaxFreq", i)
+		}
+		if _, err := parseHexColor(band.Color); err != nil {
+			return invalidField("EQBands", band, "EQ band %d has an invalid color: %w", i, err)
+		}
+	}
+	
+	// Validate accent color
+	if config.AccentColor != "" {
+		if _, err := parseHexColor(config.AccentColor); err != nil {
+			return invalidField("AccentColor", config.AccentColor, "invalid accent color: %w", err)
+		}
+		if config.AccentThreshold <= 0 || config.AccentThreshold > 1 {
+			return invalidField("AccentThreshold", config.AccentThreshold, "accent threshold must be greater than 0 and at most 1")
+		}
+	}
+	
+	// Validate glow
+	if config.GlowThreshold < 0 || config.GlowThreshold > 1 {
+		return invalidField("GlowThreshold", config.GlowThreshold, "glow threshold must be between 0 and 1")
+	}
+	if config.GlowOpacity < 0 || config.GlowOpacity > 1 {
+		return invalidField("GlowOpacity", config.GlowOpacity, "glow opacity must be between 0 and 1")
+	}
+	if config.GlowColor != "" && config.GlowColor != glowSchemeColor {
+		if _, err := parseHexColor(config.GlowColor); err != nil {
+			return invalidField("GlowColor", config.GlowColor, "invalid glow color: %w", err)
+		}
+	}
+	
+	// Validate shadow
+	if config.Shadow {
+		if config.ShadowColor != "" {
+			if _, err := parseHexColor(config.ShadowColor); err != nil {
+				return invalidField("ShadowColor", config.ShadowColor, "invalid shadow color: %w", err)
+			}
+		}
+		if config.ShadowOffset < -100 || config.ShadowOffset > 100 {
+			return invalidField("ShadowOffset", config.ShadowOffset, "shadow offset must be between -100 and 100")
+		}
+		if config.ShadowBlur < 0 || config.ShadowBlur > 50 {
+			return invalidField("ShadowBlur", config.ShadowBlur, "shadow blur must be between 0 and 50")
+		}
+	}
+	
+	// Validate background image
+	if config.BackgroundImage != "" {
+		if _, err := os.Stat(config.BackgroundImage); os.IsNotExist(err) {
+			return invalidField("BackgroundImage", config.BackgroundImage, "background image not found: %s", config.BackgroundImage)
+		}
+	}
+	if config.BackgroundFit != "" && !config.BackgroundFit.IsValid() {
+		return invalidField("BackgroundFit", config.BackgroundFit, "invalid background fit: %s", config.BackgroundFit)
+	}
+	if config.BackgroundBlur < 0 || config.BackgroundBlur > 100 {
+		return invalidField("BackgroundBlur", config.BackgroundBlur, "background blur must be between 0 and 100")
+	}
+	
+	// Validate watermark
+	if config.WatermarkFile != "" {
+		if _, err := os.Stat(config.WatermarkFile); os.IsNotExist(err) {
+			return invalidField("WatermarkFile", config.WatermarkFile, "watermark file not found: %s", config.WatermarkFile)
+		}
+		switch strings.ToLower(filepath.Ext(config.WatermarkFile)) {
+		case ".png", ".jpg", ".jpeg":
+		default:
+			return invalidField("WatermarkFile", config.WatermarkFile, "unsupported watermark format: %s (use PNG or JPEG)", config.WatermarkFile)
+		}
+		if config.WatermarkPosition != "" && !config.WatermarkPosition.IsValid() {
+			return invalidField("WatermarkPosition", config.WatermarkPosition, "invalid watermark position: %s", config.WatermarkPosition)
+		}
+		if config.WatermarkOpacity <= 0 || config.WatermarkOpacity > 1 {
+			return invalidField("WatermarkOpacity", config.WatermarkOpacity, "watermark opacity must be greater than 0 and at most 1")
+		}
+	}
+	
+	// Validate center image and hole
+	if config.CenterImage != "" {
+		if _, err := os.Stat(config.CenterImage); os.IsNotExist(err) {
+			return invalidField("CenterImage", config.CenterImage, "center image not found: %s", config.CenterImage)
+		}
+		switch strings.ToLower(filepath.Ext(config.CenterImage)) {
+		case ".png", ".jpg", ".jpeg":
+		default:
+			return invalidField("CenterImage", config.CenterImage, "unsupported center image format: %s (use PNG or JPEG)", config.CenterImage)
+		}
+	}
+	if config.CenterRadius < 0 {
+		return invalidField("CenterRadius", config.CenterRadius, "center radius cannot be negative")
+	}
+	if outer := float64(min(config.Width, config.Height))/2 - 50; config.CenterRadius >= outer {
+		return invalidField("CenterRadius", config.CenterRadius, "center radius must be less than the outer radius (%.0f)", outer)
+	}
+	if config.CenterPulse < 0 || config.CenterPulse > 1 {
+		return invalidField("CenterPulse", config.CenterPulse, "center pulse must be between 0 and 1")
+	}
+	
+	// Validate title; the font is loaded here so a bad file fails before rendering
+	if config.TitleText != "" {
+		if config.TitleSize <= 0 {
+			return invalidField("TitleSize", config.TitleSize, "title size must be positive")
+		}
+		if config.TitleFont != "" {
+			if _, err := gg.LoadFontFace(config.TitleFont, config.TitleSize); err != nil {
+				return invalidField("TitleFont", config.TitleFont, "invalid title font %s: %w", config.TitleFont, err)
+			}
+		}
+		if config.TitleColor != "" {
+			if _, err := parseHexColor(config.TitleColor); err != nil {
+				return invalidField("TitleColor", config.TitleColor, "invalid title color: %w", err)
+			}
+		}
+		if config.TitlePosition != "" && !config.TitlePosition.IsValid() {
+			return invalidField("TitlePosition", config.TitlePosition, "invalid title position: %s", config.TitlePosition)
+		}
+	}
+	
+	// Validate loop count
+	if config.LoopCount < 0 {
+		return invalidField("LoopCount", config.LoopCount, "loop count cannot be negative")
+	}
+	
+	// Validate encoder settings
+	if config.VideoCRF < 0 || config.VideoCRF > 51 {
+		return invalidField("VideoCRF", config.VideoCRF, "video CRF must be between 0 and 51")
+	}
+	if config.VideoPreset != "" && !isValidPreset(config.VideoPreset) {
+		return invalidField("VideoPreset", config.VideoPreset, "invalid video preset: %s", config.VideoPreset)
+	}
+	if config.AudioBitrate != "" && !audioBitratePattern.MatchString(config.AudioBitrate) {
+		return invalidField("AudioBitrate", config.AudioBitrate, "invalid audio bitrate: %s (expected e.g. 192k)", config.AudioBitrate)
+	}
+	if config.HWAccel != "" && !config.HWAccel.IsValid() {
+		return invalidField("HWAccel", config.HWAccel, "invalid hardware acceleration: %s", config.HWAccel)
+	}
+	
+	// Validate delivery specs
+	if config.PixelFormat != "" && !slices.Contains(GetPixelFormats(), config.PixelFormat) {
+		return invalidField("PixelFormat", config.PixelFormat, "invalid pixel format: %s", config.PixelFormat)
+	}
+	if config.Profile != "" && !slices.Contains(GetVideoProfiles(), config.Profile) {
+		return invalidField("Profile", config.Profile, "invalid video profile: %s", config.Profile)
+	}
+	if config.Level != "" && !slices.Contains(GetVideoLevels(), config.Level) {
+		return invalidField("Level", config.Level, "invalid video level: %s", config.Level)
+	}
+	if config.Profile != "" && !slices.Contains(profilePixelFormats[config.Profile], orDefault(config.PixelFormat, "yuv420p")) {
+		return invalidField("Profile", config.Profile, "profile %s can't encode %s; use %s", config.Profile, orDefault(config.PixelFormat, "yuv420p"), strings.Join(profilePixelFormats[config.Profile], " or "))
+	}
+	if config.HWAccel != "" && config.HWAccel != HWAccelNone && orDefault(config.PixelFormat, "yuv420p") != "yuv420p" {
+		return invalidField("PixelFormat", config.PixelFormat, "hardware encoders only take yuv420p")
+	}
+	
+	// Validate output format (empty means picked by the output extension)
+	if config.OutputFormat != "" && !config.OutputFormat.IsValid() {
+		return invalidField("OutputFormat", config.OutputFormat, "invalid output format: %s", config.OutputFormat)
+	}
+	if resolveOutputFormat(string(config.OutputFormat), config.OutputFile) == "webm" {
+		// The libx264 default is swapped for VP9; anything else must fit WebM
+		if config.VideoCodec != "" && config.VideoCodec != "libx264" && !webmEncoders[config.VideoCodec] {
+			return invalidField("VideoCodec", config.VideoCodec, "video codec %s can't be written to WebM; use libvpx-vp9, libvpx or libaom-av1", config.VideoCodec)
+		}
+		if config.HWAccel != "" && config.HWAccel != HWAccelNone {
+			return invalidField("HWAccel", config.HWAccel, "hardware encoders produce H.264, which WebM can't hold")
+		}
+		if config.Profile != "" || config.Level != "" {
+			return invalidField("Profile", config.Profile, "profile and level are H.264 settings and can't be used with WebM")
+		}
+	}
+	
+	// Validate overlay mode
+	if config.OverlayOnInput {
+		switch format := resolveOutputFormat(string(config.OutputFormat), config.OutputFile); format {
+		case "gif", "webp":
+			return invalidField("OverlayOnInput", config.OverlayOnInput, "overlay on input requires a video output, not %s", format)
+		}
+		if len(config.Segments) > 0 {
+			return invalidField("OverlayOnInput", config.OverlayOnInput, "overlay on input cannot be combined with segments")
+		}
 	}
 	
 	return nil
 }
 
+// webmEncoders are the ffmpeg video encoders whose output WebM can hold
+var webmEncoders = map[string]bool{
+	"libvpx-vp9": true,
+	"libvpx":     true,
+	"libaom-av1": true,
+	"libsvtav1":  true,
+}
+
+// audioBitratePattern matches ffmpeg bitrates such as 128k or 320000
+var audioBitratePattern = regexp.MustCompile(`^[0-9]+[kK]?$`)
+
+// profilePixelFormats lists the pixel formats each H.264 profile can encode
+var profilePixelFormats = map[string][]string{
+	"baseline": {"yuv420p"},
+	"main":     {"yuv420p"},
+	"high":     {"yuv420p"},
+	"high10":   {"yuv420p", "yuv420p10le"},
+	"high422":  {"yuv420p", "yuv422p", "yuv420p10le", "yuv422p10le"},
+	"high444":  {"yuv420p", "yuv422p", "yuv444p", "yuv420p10le", "yuv422p10le"},
+}
+
+// isValidPreset checks the preset against the x264/x265 preset names
+func isValidPreset(preset string) bool {
+	for _, p := range GetVideoPresets() {
+		if p == preset {
+			return true
+		}
+	}
+	return false
+}
+
 // GetSupportedFormats returns the supported audio formats
 func GetSupportedFormats() []string {
 	return []string{
@@ -159,7 +1463,8 @@ func GetColorSchemes() []ColorScheme {
 	return []ColorScheme{
 		ColorSchemeRainbow, ColorSchemeFire, ColorSchemeOcean, ColorSchemePurple,
 		ColorSchemeNeon, ColorSchemeMonochrome, ColorSchemeSunset, ColorSchemeForest,
-		ColorSchemeWhite,
+		ColorSchemeIce, ColorSchemeLava, ColorSchemeRetro, ColorSchemeCosmic,
+		ColorSchemePastel, ColorSchemeMatrix, ColorSchemeWhite,
 	}
 }
 
@@ -168,6 +1473,8 @@ func GetVisualizationTypes() []VisType {
 	return []VisType{
 		VisTypeBars, VisTypeCircular, VisTypeWave, VisTypeRadial,
 		VisTypeLine, VisTypeDots, VisTypeMirror, VisTypeSpiral,
+		VisTypeSpectrogram, VisTypeOscilloscope, VisTypeCircularWave,
+		VisTypeVUMeter, VisTypeHBars, VisTypeArea, VisTypeButterfly,
 	}
 }
 
@@ -179,9 +1486,139 @@ func GetBackgroundColors() []BGColor {
 	}
 }
 
+// GetAmplitudeScales returns all available amplitude scales
+func GetAmplitudeScales() []AmplitudeScale {
+	return []AmplitudeScale{
+		AmplitudeScaleLinear, AmplitudeScaleLog, AmplitudeScaleDB,
+	}
+}
+
+// GetBinAggregations returns all available bin aggregations
+func GetBinAggregations() []BinAggregation {
+	return []BinAggregation{
+		BinAggregationAverage, BinAggregationMax, BinAggregationSum,
+	}
+}
+
+// GetFreqScales returns all available frequency scales
+func GetFreqScales() []FreqScale {
+	return []FreqScale{
+		FreqScaleLog, FreqScaleLinear, FreqScaleMel,
+	}
+}
+
+// GetWeightings returns all available loudness weightings
+func GetWeightings() []Weighting {
+	return []Weighting{
+		WeightingNone, WeightingAWeight,
+	}
+}
+
+// GetBackgroundFits returns all available background fits
+func GetBackgroundFits() []BackgroundFit {
+	return []BackgroundFit{
+		BackgroundFitFill, BackgroundFitContain, BackgroundFitCover,
+	}
+}
+
+// GetHWAccels returns all available hardware encoders
+func GetHWAccels() []HWAccel {
+	return []HWAccel{
+		HWAccelNone, HWAccelNVENC, HWAccelVideoToolbox, HWAccelQSV,
+	}
+}
+
+// GetOutputFormats returns all available output formats
+func GetOutputFormats() []OutputFormat {
+	return []OutputFormat{
+		OutputFormatMP4, OutputFormatWebM, OutputFormatGIF, OutputFormatWebP,
+	}
+}
+
+// GetColorModes returns all available color modes
+func GetColorModes() []ColorMode {
+	return []ColorMode{
+		ColorModeMagnitude, ColorModeFrequency,
+	}
+}
+
+// GetPositions returns all available overlay positions
+func GetPositions() []Position {
+	return []Position{
+		PositionTopLeft, PositionTopRight, PositionBottomLeft,
+		PositionBottomRight, PositionCenter,
+	}
+}
+
+// GetLineCaps returns all available line caps
+func GetLineCaps() []LineCap {
+	return []LineCap{
+		LineCapRound, LineCapButt, LineCapSquare,
+	}
+}
+
+// GetInterpolations returns all available line interpolations
+func GetInterpolations() []Interpolation {
+	return []Interpolation{
+		InterpolationNone, InterpolationLinear, InterpolationCubic,
+	}
+}
+
+// GetHeightCurves returns all available bar height curves
+func GetHeightCurves() []HeightCurve {
+	return []HeightCurve{
+		HeightCurveLinear, HeightCurveSqrt, HeightCurveSquare,
+	}
+}
+
+// GetSampleFormats returns all available analysis sample formats
+func GetSampleFormats() []SampleFormat {
+	return []SampleFormat{
+		SampleFormatF32, SampleFormatS16,
+	}
+}
+
+// GetFrameFormats returns all available frame formats
+func GetFrameFormats() []FrameFormat {
+	return []FrameFormat{
+		FrameFormatPNG, FrameFormatJPEG,
+	}
+}
+
+// GetOrientations returns all available bar orientations
+func GetOrientations() []Orientation {
+	return []Orientation{
+		OrientationBottomUp, OrientationTopDown, OrientationLeftRight, OrientationRightLeft,
+	}
+}
+
 // GetProcessTypes returns all available process types
 func GetProcessTypes() []ProcessType {
 	return []ProcessType{
-		ProcessTypeFast, ProcessTypeParallel,
+		ProcessTypeFast, ProcessTypeParallel, ProcessTypePipe,
+		ProcessTypeParallelPipe,
+	}
+}
+
+// GetVideoPresets returns the accepted encoder presets, fastest first
+func GetVideoPresets() []string {
+	return []string{
+		"ultrafast", "superfast", "veryfast", "faster", "fast",
+		"medium", "slow", "slower", "veryslow", "placebo",
 	}
-}
\ No newline at end of file
+}
+
+// GetPixelFormats returns the accepted output pixel formats
+func GetPixelFormats() []string {
+	return []string{"yuv420p", "yuv422p", "yuv444p", "yuv420p10le", "yuv422p10le"}
+}
+
+// GetVideoProfiles returns the accepted H.264 profiles
+func GetVideoProfiles() []string {
+	return []string{"baseline", "main", "high", "high10", "high422", "high444"}
+}
+
+// GetVideoLevels returns the accepted H.264 levels
+func GetVideoLevels() []string {
+	return []string{"3.0", "3.1", "3.2", "4.0", "4.1", "4.2", "5.0", "5.1", "5.2"}
+}
@@ -0,0 +1,169 @@
+package audiospectrum
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestGenerateConcurrent runs two renders at once and checks each encodes
+// its own frames, which needs every render to get its own temp directory
+func TestGenerateConcurrent(t *testing.T) {
+	tool := fakeTool(t, fakeFFmpeg)
+	configs := make([]*Config, 2)
+	want := make([][]byte, len(configs))
+	for i, freq := range []float64{440, 3000} {
+		config := testConfig(t)
+		config.InputFile = writeTestWAV(t, 1, 44100, freq)
+		config.FFmpegPath = tool
+		config.FFprobePath = tool
+		configs[i] = config
+
+		// The fake ffmpeg encodes the frame files concatenated in order
+		dir := t.TempDir()
+		if err := NewVisualizer(newVisualizerConfig(config)).CreateFrames(dir); err != nil {
+			t.Fatal(err)
+		}
+		frames, _ := filepath.Glob(filepath.Join(dir, "frame_*.png"))
+		for _, frame := range frames {
+			data, err := os.ReadFile(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want[i] = append(want[i], data...)
+		}
+	}
+	if bytes.Equal(want[0], want[1]) {
+		t.Fatal("test renders are identical, so a collision would go unnoticed")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(configs))
+	for i, config := range configs {
+		wg.Add(1)
+		go func(i int, config *Config) {
+			defer wg.Done()
+			errs[i] = Generate(config)
+		}(i, config)
+	}
+	wg.Wait()
+
+	for i, config := range configs {
+		if errs[i] != nil {
+			t.Fatalf("render %d: %v", i, errs[i])
+		}
+		got, err := os.ReadFile(config.OutputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want[i]) {
+			t.Errorf("render %d encoded %d bytes of frames that don't match its own %d", i, len(got), len(want[i]))
+		}
+	}
+}
+
+// TestValidateConfigError checks invalid options are reported as a
+// ConfigError naming the field, matching ErrInvalidConfig through the
+// wrapping Generate adds
+func TestValidateConfigError(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(c *Config)
+		wantField string
+		wantCause bool // Whether the error wraps an underlying parse error
+	}{
+		{"FPS", func(c *Config) { c.FPS = 0 }, "FPS", false},
+		{"duration", func(c *Config) { c.Duration = -1 }, "Duration", false},
+		{"bar count", func(c *Config) { c.BarCount = 4 }, "BarCount", false},
+		{"width", func(c *Config) { c.Width = 100 }, "Width", false},
+		{"color scheme", func(c *Config) { c.ColorScheme = "plaid" }, "ColorScheme", false},
+		{"process type", func(c *Config) { c.ProcessType = "magic" }, "ProcessType", false},
+		{"background color", func(c *Config) { c.BGColor = "#zzzzzz" }, "BGColor", true},
+		{"padding", func(c *Config) { c.PadToDuration = true }, "PadToDuration", false},
+		{"frame format", func(c *Config) { c.FrameFormat = "gif" }, "FrameFormat", false},
+	}
+
+	input := writeTestWAV(t, 0.1, 44100, 440)
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.InputFile = input
+		tt.modify(config)
+
+		err := checkConfig(config)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: got %v, want an invalid configuration error", tt.name, err)
+			continue
+		}
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%s: %T is not a ConfigError", tt.name, err)
+			continue
+		}
+		if configErr.Field != tt.wantField {
+			t.Errorf("%s: got field %s, want %s", tt.name, configErr.Field, tt.wantField)
+		}
+		if configErr.Reason == "" || configErr.Error() != configErr.Reason {
+			t.Errorf("%s: got reason %q and message %q", tt.name, configErr.Reason, configErr.Error())
+		}
+		if gotCause := errors.Unwrap(configErr) != nil; gotCause != tt.wantCause {
+			t.Errorf("%s: wraps a cause %v, want %v", tt.name, gotCause, tt.wantCause)
+		}
+	}
+
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config: %v", err)
+	}
+}
+
+// TestCheckConfigInputFile checks a missing input is a ConfigError too
+func TestCheckConfigInputFile(t *testing.T) {
+	for _, input := range []string{"", filepath.Join(t.TempDir(), "missing.wav")} {
+		config := DefaultConfig()
+		config.InputFile = input
+
+		var configErr *ConfigError
+		err := checkConfig(config)
+		if !errors.As(err, &configErr) || configErr.Field != "InputFile" {
+			t.Errorf("input %q: got %v, want an InputFile ConfigError", input, err)
+		} else if !strings.HasPrefix(err.Error(), "invalid configuration: ") {
+			t.Errorf("input %q: got %q, want it reported as an invalid configuration", input, err)
+		}
+	}
+}
+
+// TestCheckReaderConfig checks the options a stream can't support are
+// rejected with the same error shape as any other invalid option
+func TestCheckReaderConfig(t *testing.T) {
+	tests := []struct {
+		modify    func(c *Config)
+		wantField string
+	}{
+		{func(c *Config) { c.FPS = 0 }, "FPS"},
+		{func(c *Config) { c.Segments = []Segment{{Start: 0, Duration: 1}} }, "Segments"},
+		{func(c *Config) { c.OverlayOnInput = true }, "OverlayOnInput"},
+		{func(c *Config) { c.AutoBackground = true }, "AutoBackground"},
+		{func(c *Config) { c.CacheDir = t.TempDir() }, "CacheDir"},
+		{func(c *Config) { c.WriteManifest = "manifest.json" }, "WriteManifest"},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.modify(config)
+
+		var configErr *ConfigError
+		err := checkReaderConfig(config)
+		if !errors.As(err, &configErr) || configErr.Field != tt.wantField {
+			t.Errorf("got %v, want a %s ConfigError", err, tt.wantField)
+		} else if !errors.Is(err, ErrInvalidConfig) || !strings.HasPrefix(err.Error(), "invalid configuration: ") {
+			t.Errorf("%s: got %q, want it reported as an invalid configuration", tt.wantField, err)
+		}
+	}
+
+	if err := checkReaderConfig(DefaultConfig()); err != nil {
+		t.Errorf("default config: %v", err)
+	}
+}
@@ -35,6 +35,14 @@ const (
 	VisTypeDots     VisType = "dots"     // Particle/dots effect
 	VisTypeMirror   VisType = "mirror"   // Mirrored bars from center
 	VisTypeSpiral   VisType = "spiral"   // Spiral pattern
+
+	VisTypeSpectrogram  VisType = "spectrogram"   // Scrolling frequency-vs-time waterfall
+	VisTypeOscilloscope VisType = "oscilloscope"  // Raw time-domain waveform trace
+	VisTypeCircularWave VisType = "circular-wave" // Raw waveform wrapped around a pulsing ring
+	VisTypeVUMeter      VisType = "vu-meter"      // Horizontal LED level meter of overall loudness
+	VisTypeHBars        VisType = "hbars"         // Labelled horizontal bars stacked top to bottom, like a mixer meter
+	VisTypeArea         VisType = "area"          // Filled envelope under the line spectrum, like an audio editor
+	VisTypeButterfly    VisType = "butterfly"     // Mirror bars reflected about the vertical center, lows at both edges
 )
 
 // BGColor represents the available background colors
@@ -57,6 +65,162 @@ type ProcessType string
 const (
 	ProcessTypeFast     ProcessType = "fast"     // Sequential processing
 	ProcessTypeParallel ProcessType = "parallel" // Parallel processing using all CPU cores
+	ProcessTypePipe     ProcessType = "pipe"     // Stream raw frames to ffmpeg without temp files
+
+	ProcessTypeParallelPipe ProcessType = "parallel-pipe" // Parallel rendering streamed to ffmpeg in order
+)
+
+// AmplitudeScale represents how binned magnitudes are mapped to the 0..1 display range
+type AmplitudeScale string
+
+// Available amplitude scales
+const (
+	AmplitudeScaleLinear AmplitudeScale = "linear" // Raw normalized magnitude
+	AmplitudeScaleLog    AmplitudeScale = "log"    // Fixed log10 curve (default)
+	AmplitudeScaleDB     AmplitudeScale = "db"     // Decibels mapped from DBFloor..0 dB
+)
+
+// BinAggregation represents how FFT bins are combined into a single bar
+type BinAggregation string
+
+// Available bin aggregations
+const (
+	BinAggregationAverage BinAggregation = "average" // Mean magnitude of the range (default)
+	BinAggregationMax     BinAggregation = "max"     // Strongest bin in the range
+	BinAggregationSum     BinAggregation = "sum"     // Total magnitude of the range
+)
+
+// FreqScale represents how the analysed frequency range is divided into bars
+type FreqScale string
+
+// Available frequency scales
+const (
+	FreqScaleLog    FreqScale = "log"    // Equal ratios per bar (default)
+	FreqScaleLinear FreqScale = "linear" // Equal Hz per bar
+	FreqScaleMel    FreqScale = "mel"    // Equal steps on the mel scale
+)
+
+// Weighting represents the loudness curve applied to FFT bins before binning
+type Weighting string
+
+// Available weightings
+const (
+	WeightingNone    Weighting = "none"     // Flat magnitudes (default)
+	WeightingAWeight Weighting = "a-weight" // IEC 61672 A-weighting, closer to perceived loudness
+)
+
+// BackgroundFit represents how a background image is scaled to the frame
+type BackgroundFit string
+
+// Available background fits
+const (
+	BackgroundFitFill    BackgroundFit = "fill"    // Stretch to the frame, ignoring aspect ratio
+	BackgroundFitContain BackgroundFit = "contain" // Fit inside the frame, letterboxed with BGColor
+	BackgroundFitCover   BackgroundFit = "cover"   // Fill the frame, cropping the overflow (default)
+)
+
+// HWAccel represents the hardware video encoder to use
+type HWAccel string
+
+// Available hardware encoders
+const (
+	HWAccelNone         HWAccel = "none"         // Software encoding with VideoCodec (default)
+	HWAccelNVENC        HWAccel = "nvenc"        // NVIDIA NVENC (h264_nvenc)
+	HWAccelVideoToolbox HWAccel = "videotoolbox" // Apple VideoToolbox (h264_videotoolbox)
+	HWAccelQSV          HWAccel = "qsv"          // Intel Quick Sync (h264_qsv)
+)
+
+// OutputFormat represents the container the video is written as
+type OutputFormat string
+
+// Available output formats
+const (
+	OutputFormatMP4  OutputFormat = "mp4"  // H.264 and AAC
+	OutputFormatWebM OutputFormat = "webm" // VP9 and Opus
+	OutputFormatGIF  OutputFormat = "gif"  // Silent palette-based animation
+	OutputFormatWebP OutputFormat = "webp" // Silent animated WebP
+)
+
+// ColorMode represents what drives the color of each bar
+type ColorMode string
+
+// Available color modes
+const (
+	ColorModeMagnitude ColorMode = "magnitude" // Color scheme follows loudness (default)
+	ColorModeFrequency ColorMode = "frequency" // Hue follows the bar's frequency band; loudness sets brightness
+)
+
+// Position represents where an overlay such as the watermark sits in the frame
+type Position string
+
+// Available positions
+const (
+	PositionTopLeft     Position = "top-left"
+	PositionTopRight    Position = "top-right"
+	PositionBottomLeft  Position = "bottom-left"
+	PositionBottomRight Position = "bottom-right"
+	PositionCenter      Position = "center"
+)
+
+// LineCap represents how the ends of stroked lines are drawn
+type LineCap string
+
+// Available line caps
+const (
+	LineCapRound  LineCap = "round"  // Rounded ends (default)
+	LineCapButt   LineCap = "butt"   // Flat ends at the endpoint
+	LineCapSquare LineCap = "square" // Flat ends extended by half the line width
+)
+
+// Orientation represents which edge the bars of the bars visualization grow from
+type Orientation string
+
+// Available orientations
+const (
+	OrientationBottomUp  Orientation = "bottom-up"  // Bars grow up from the bottom edge (default)
+	OrientationTopDown   Orientation = "top-down"   // Bars hang down from the top edge
+	OrientationLeftRight Orientation = "left-right" // Bars grow right from the left edge, lowest band at the top
+	OrientationRightLeft Orientation = "right-left" // Bars grow left from the right edge, lowest band at the top
+)
+
+// HeightCurve represents how a band's level maps to the length of its bar
+type HeightCurve string
+
+// Available height curves
+const (
+	HeightCurveLinear HeightCurve = "linear" // Length proportional to level (default)
+	HeightCurveSqrt   HeightCurve = "sqrt"   // Lifts quiet levels so small signals stand out
+	HeightCurveSquare HeightCurve = "square" // Flattens quiet levels to emphasize peaks
+)
+
+// Interpolation represents how the line, wave and area types fill in points
+// between bands
+type Interpolation string
+
+// Available interpolations
+const (
+	InterpolationNone   Interpolation = "none"   // One point per band (default)
+	InterpolationLinear Interpolation = "linear" // Straight ramps between bands
+	InterpolationCubic  Interpolation = "cubic"  // Catmull-Rom curve through the bands
+)
+
+// SampleFormat represents the PCM format ffmpeg decodes the audio to for
+// analysis
+type SampleFormat string
+
+// Available sample formats
+const (
+	SampleFormatF32 SampleFormat = "f32" // 32-bit float (default)
+	SampleFormatS16 SampleFormat = "s16" // 16-bit integer, half the raw audio size
+)
+
+// FrameFormat represents the image format PNG-based renders write frames in
+type FrameFormat string
+
+// Available frame formats
+const (
+	FrameFormatPNG  FrameFormat = "png"  // Lossless (default)
+	FrameFormatJPEG FrameFormat = "jpeg" // Lossy but much smaller; no transparency
 )
 
 // String returns the string representation of ColorScheme
@@ -85,7 +249,9 @@ func (v VisType) String() string {
 func (v VisType) IsValid() bool {
 	switch v {
 	case VisTypeBars, VisTypeCircular, VisTypeWave, VisTypeRadial,
-		VisTypeLine, VisTypeDots, VisTypeMirror, VisTypeSpiral:
+		VisTypeLine, VisTypeDots, VisTypeMirror, VisTypeSpiral,
+		VisTypeSpectrogram, VisTypeOscilloscope, VisTypeCircularWave,
+		VisTypeVUMeter, VisTypeHBars, VisTypeArea, VisTypeButterfly:
 		return true
 	}
 	return false
@@ -112,5 +278,179 @@ func (p ProcessType) String() string {
 
 // IsValid checks if the process type is valid
 func (p ProcessType) IsValid() bool {
-	return p == ProcessTypeFast || p == ProcessTypeParallel
-}
\ No newline at end of file
+	switch p {
+	case ProcessTypeFast, ProcessTypeParallel, ProcessTypePipe, ProcessTypeParallelPipe:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of AmplitudeScale
+func (a AmplitudeScale) String() string {
+	return string(a)
+}
+
+// IsValid checks if the amplitude scale is valid
+func (a AmplitudeScale) IsValid() bool {
+	switch a {
+	case AmplitudeScaleLinear, AmplitudeScaleLog, AmplitudeScaleDB:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of BinAggregation
+func (b BinAggregation) String() string {
+	return string(b)
+}
+
+// IsValid checks if the bin aggregation is valid
+func (b BinAggregation) IsValid() bool {
+	return b == BinAggregationAverage || b == BinAggregationMax || b == BinAggregationSum
+}
+
+// String returns the string representation of BackgroundFit
+func (b BackgroundFit) String() string {
+	return string(b)
+}
+
+// IsValid checks if the background fit is valid
+func (b BackgroundFit) IsValid() bool {
+	return b == BackgroundFitFill || b == BackgroundFitContain || b == BackgroundFitCover
+}
+
+// String returns the string representation of HWAccel
+func (h HWAccel) String() string {
+	return string(h)
+}
+
+// IsValid checks if the hardware encoder is valid
+func (h HWAccel) IsValid() bool {
+	switch h {
+	case HWAccelNone, HWAccelNVENC, HWAccelVideoToolbox, HWAccelQSV:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of Position
+func (p Position) String() string {
+	return string(p)
+}
+
+// IsValid checks if the position is valid
+func (p Position) IsValid() bool {
+	switch p {
+	case PositionTopLeft, PositionTopRight, PositionBottomLeft, PositionBottomRight, PositionCenter:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of ColorMode
+func (c ColorMode) String() string {
+	return string(c)
+}
+
+// IsValid checks if the color mode is valid
+func (c ColorMode) IsValid() bool {
+	return c == ColorModeMagnitude || c == ColorModeFrequency
+}
+
+// String returns the string representation of FreqScale
+func (f FreqScale) String() string {
+	return string(f)
+}
+
+// IsValid checks if the frequency scale is valid
+func (f FreqScale) IsValid() bool {
+	return f == FreqScaleLog || f == FreqScaleLinear || f == FreqScaleMel
+}
+
+// String returns the string representation of Orientation
+func (o Orientation) String() string {
+	return string(o)
+}
+
+// IsValid checks if the orientation is valid
+func (o Orientation) IsValid() bool {
+	switch o {
+	case OrientationBottomUp, OrientationTopDown, OrientationLeftRight, OrientationRightLeft:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of LineCap
+func (l LineCap) String() string {
+	return string(l)
+}
+
+// IsValid checks if the line cap is valid
+func (l LineCap) IsValid() bool {
+	return l == LineCapRound || l == LineCapButt || l == LineCapSquare
+}
+
+// String returns the string representation of Weighting
+func (w Weighting) String() string {
+	return string(w)
+}
+
+// IsValid checks if the weighting is valid
+func (w Weighting) IsValid() bool {
+	return w == WeightingNone || w == WeightingAWeight
+}
+
+// String returns the string representation of OutputFormat
+func (o OutputFormat) String() string {
+	return string(o)
+}
+
+// IsValid checks if the output format is valid
+func (o OutputFormat) IsValid() bool {
+	switch o {
+	case OutputFormatMP4, OutputFormatWebM, OutputFormatGIF, OutputFormatWebP:
+		return true
+	}
+	return false
+}
+
+// String returns the string representation of FrameFormat
+func (f FrameFormat) String() string {
+	return string(f)
+}
+
+// IsValid checks if the frame format is valid
+func (f FrameFormat) IsValid() bool {
+	return f == FrameFormatPNG || f == FrameFormatJPEG
+}
+
+// String returns the string representation of HeightCurve
+func (h HeightCurve) String() string {
+	return string(h)
+}
+
+// IsValid checks if the height curve is valid
+func (h HeightCurve) IsValid() bool {
+	return h == HeightCurveLinear || h == HeightCurveSqrt || h == HeightCurveSquare
+}
+
+// String returns the string representation of SampleFormat
+func (s SampleFormat) String() string {
+	return string(s)
+}
+
+// IsValid checks if the sample format is valid
+func (s SampleFormat) IsValid() bool {
+	return s == SampleFormatF32 || s == SampleFormatS16
+}
+
+// String returns the string representation of Interpolation
+func (i Interpolation) String() string {
+	return string(i)
+}
+
+// IsValid checks if the interpolation is valid
+func (i Interpolation) IsValid() bool {
+	return i == InterpolationNone || i == InterpolationLinear || i == InterpolationCubic
+}
@@ -2,18 +2,24 @@ package audiospectrum
 
 import (
 	"fmt"
+	"image"
 	"image/color"
+	"image/draw"
+	"io"
 	"math"
 	"math/cmplx"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
 	"github.com/mjibson/go-dsp/fft"
 )
 
@@ -22,6 +28,7 @@ type VisualizerConfig struct {
 	InputFile    string
 	OutputFile   string
 	FPS          int
+	StartTime    float64
 	Duration     float64
 	BarCount     int
 	ColorScheme  string
@@ -30,6 +37,139 @@ type VisualizerConfig struct {
 	Width        int
 	Height       int
 	ProcessType  string
+
+	Workers       int
+	ReorderWindow int
+	FrameLimit    int
+	FrameFormat   FrameFormat
+	FrameQuality  int
+
+	FFmpegPath  string
+	FFprobePath string
+
+	SampleRate     int
+	SampleFormat   string
+	HopLength      int
+	AmplitudeScale string
+	DBFloor        float64
+	Smoothing      float64
+	Sensitivity    float64
+	NoiseGate      float64
+	BinAggregation string
+	FreqScale      string
+	Weighting      string
+	FractionalBins bool
+
+	LogScaleMultiplier float64
+	LogScaleDivisor    float64
+
+	Normalize bool
+	AGC       bool
+	AGCWindow float64
+
+	SurroundDownmix bool
+
+	BarGap    float64
+	BarBevel  bool
+	PeakHold  bool
+	PeakDecay float64
+
+	BarGradient bool
+
+	BarCornerRadius float64
+	MinBarHeight    float64
+
+	SilenceThreshold float64
+	HeightCurve      string
+
+	Reflection        bool
+	ReflectionOpacity float64
+
+	Orientation  string
+	ShowFreqAxis bool
+	Symmetric    bool
+
+	SegmentedBars bool
+	SegmentCount  int
+
+	SmoothLine bool
+	LineCap    string
+
+	Interpolation string
+
+	SpiralTurns  float64
+	DotsMaxCount int
+	DotSize      float64
+
+	RotationSpeed float64
+
+	VisualSpeed float64
+
+	ColorMode string
+	HueSpan   float64
+	BaseHue   float64
+
+	ColorCycleSpeed float64
+
+	AccentColor     string
+	AccentThreshold float64
+
+	EQBands []EQBand
+
+	GlowThreshold float64
+	GlowOpacity   float64
+	GlowColor     string
+
+	Shadow       bool
+	ShadowColor  string
+	ShadowOffset float64
+	ShadowBlur   float64
+
+	BackgroundImage string
+	BackgroundFit   string
+	BackgroundBlur  float64
+	AutoBackground  bool
+
+	BGGradientTop    string
+	BGGradientBottom string
+
+	WatermarkFile     string
+	WatermarkPosition string
+	WatermarkOpacity  float64
+
+	TitleText     string
+	TitleFont     string
+	TitleSize     float64
+	TitleColor    string
+	TitlePosition string
+	
+	CenterImage  string
+	CenterRadius float64
+	CenterPulse  float64
+
+	OutputFormat string
+	LoopCount    int
+	VideoCodec   string
+	VideoCRF     int
+	VideoPreset  string
+	AudioBitrate string
+	HWAccel      string
+
+	PixelFormat string
+	Profile     string
+	Level       string
+
+	NoOverwrite   bool
+	AutoIncrement bool
+
+	OverlayOnInput bool
+
+	CacheDir string
+
+	Segments []Segment
+	
+	LoopToDuration float64
+	PadToDuration  bool
 }
 
 // Visualizer handles the audio spectrum visualization
@@ -39,282 +179,1747 @@ type Visualizer struct {
 	sampleRate   int
 	duration     float64
 	totalFrames  int
+	sourceFrames int
+	sourceLength float64
 	spectrumData [][]float64
-	barPositions []int
+	peaks        [][]float64
+	binWeights   []float64
+	background   image.Image
+	coverArt     image.Image
+	watermark    image.Image
+	centerImage  image.Image
+	titleFont    *truetype.Font
+	cacheEntry   string
+	prior        *analysis
+	inputReader  io.Reader
+	outputWriter io.Writer
+	outputFile   string // Where CreateVideo writes: OutputFile, or the free name AutoIncrement picked
+	streamCopy   string
+	barPositions []float64
 	centerX      int
 	centerY      int
-	barWidth     int
+	barWidth     float64
 	windowSize   int
+	videoEncoder string
+	accentColor  color.Color
+	glowColor    color.Color
+	shadowColor  color.Color
+	barFreqs     []float64
+	eqColors     []color.Color
+}
+
+// NewVisualizerChecked creates a new visualizer instance like NewVisualizer,
+// but returns an error for a config that would divide by zero or give bars
+// no width. Use it when building a VisualizerConfig directly, which skips the
+// validation Generate does.
+func NewVisualizerChecked(config *VisualizerConfig) (*Visualizer, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config is required")
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions: %dx%d", config.Width, config.Height)
+	}
+	if config.FPS <= 0 {
+		return nil, fmt.Errorf("FPS must be positive")
+	}
+	if config.BarCount <= 0 {
+		return nil, fmt.Errorf("bar count must be positive")
+	}
+	if usesBarSlots(config.VizType) && config.BarCount > config.Width {
+		return nil, fmt.Errorf("bar count %d exceeds width %d, bars would be 0 pixels wide", config.BarCount, config.Width)
+	}
+	
+	return NewVisualizer(config), nil
 }
 
 // NewVisualizer creates a new visualizer instance
 func NewVisualizer(config *VisualizerConfig) *Visualizer {
 	v := &Visualizer{
-		config:   config,
-		centerX:  config.Width / 2,
-		centerY:  config.Height / 2,
-		barWidth: config.Width / config.BarCount,
+		config:  config,
+		centerX: config.Width / 2,
+		centerY: config.Height / 2,
+	}
+	
+	if config.AccentColor != "" {
+		v.accentColor, _ = parseHexColor(config.AccentColor)
+	}
+	
+	// A nil glow color means each part glows in its own scheme color
+	v.glowColor = color.White
+	if config.GlowColor == glowSchemeColor {
+		v.glowColor = nil
+	} else if config.GlowColor != "" {
+		v.glowColor, _ = parseHexColor(config.GlowColor)
 	}
 	
-	// Pre-calculate bar positions
-	v.barPositions = make([]int, config.BarCount)
-	for i := 0; i < config.BarCount; i++ {
-		v.barPositions[i] = i * v.barWidth
+	v.shadowColor = color.Black
+	if config.ShadowColor != "" {
+		v.shadowColor, _ = parseHexColor(config.ShadowColor)
+	}
+	
+	// Pre-calculate bar positions as floats so the bars span the full width;
+	// the angular and full-width types lay themselves out
+	if usesBarSlots(config.VizType) && config.BarCount > 0 {
+		v.barWidth = float64(config.Width) / float64(config.BarCount)
+		v.barPositions = make([]float64, config.BarCount)
+		for i := 0; i < config.BarCount; i++ {
+			v.barPositions[i] = float64(i) * v.barWidth
+		}
 	}
 	
 	return v
 }
 
+// claimOutput returns the path to write the video to, applying NoOverwrite
+// and AutoIncrement to an OutputFile that already exists before any audio is
+// decoded or frames are rendered
+func (v *Visualizer) claimOutput() (string, error) {
+	path := v.config.OutputFile
+	if path == pipeOutput || (!v.config.NoOverwrite && !v.config.AutoIncrement) {
+		return path, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return path, nil
+	}
+	if !v.config.AutoIncrement {
+		return "", fmt.Errorf("output file %s already exists; disable NoOverwrite or enable AutoIncrement", path)
+	}
+	
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, n, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			fmt.Printf("Output file %s exists, writing %s\n", path, candidate)
+			return candidate, nil
+		}
+	}
+}
+
+// output returns the path the video is written to
+func (v *Visualizer) output() string {
+	if v.outputFile != "" {
+		return v.outputFile
+	}
+	return v.config.OutputFile
+}
+
+// usesBarSlots reports whether a visualization type places its bars in the
+// BarCount slots across the width, so needs barWidth and barPositions
+func usesBarSlots(vizType string) bool {
+	return vizType == "" || vizType == "bars" || vizType == "mirror" || vizType == "butterfly"
+}
+
 // CreateVideo creates the spectrum visualization video
 func (v *Visualizer) CreateVideo() error {
+	output, err := v.claimOutput()
+	if err != nil {
+		return err
+	}
+	v.outputFile = output
+	v.resolveEncoder()
+	
+	if v.config.OverlayOnInput {
+		if err := v.checkInputVideo(); err != nil {
+			return err
+		}
+	}
+	
+	// Reuse cached frames when only encoding settings changed
+	if v.config.CacheDir != "" {
+		hit, err := v.openFrameCache()
+		if err != nil {
+			return err
+		}
+		if hit {
+			if err := v.probeAudio(); err != nil {
+				return fmt.Errorf("loading audio: %w", err)
+			}
+			fmt.Printf("Using %d cached frames from %s\n", v.totalFrames, v.cacheEntry)
+			return v.assembleVideo(v.cacheEntry)
+		}
+	}
+	
 	// Load audio
 	if err := v.loadAudio(); err != nil {
 		return fmt.Errorf("loading audio: %w", err)
 	}
 	
+	if err := v.checkFrameLimit(); err != nil {
+		return err
+	}
+	
 	// Pre-compute spectrum data
 	fmt.Println("Pre-computing spectrum data...")
 	if err := v.precomputeSpectrum(); err != nil {
 		return fmt.Errorf("computing spectrum: %w", err)
 	}
 	
+	if err := v.loadBackground(); err != nil {
+		return err
+	}
+	if err := v.loadWatermark(); err != nil {
+		return err
+	}
+	if err := v.loadCenterImage(); err != nil {
+		return err
+	}
+	if err := v.loadTitleFont(); err != nil {
+		return err
+	}
+	
 	// Generate frames
 	fmt.Printf("Generating %d frames...\n", v.totalFrames)
-	if v.config.ProcessType == "parallel" {
+	switch v.config.ProcessType {
+	case "parallel":
 		return v.createVideoParallel()
+	case "pipe":
+		return v.createVideoPipe()
+	case "parallel-pipe":
+		return v.createVideoParallelPipe()
 	}
 	return v.createVideoSequential()
 }
 
-// loadAudio loads the audio file and prepares it for processing
-func (v *Visualizer) loadAudio() error {
-	// For now, we'll use ffmpeg to extract audio data
-	// In a production version, we'd use a proper audio library
+// ffmpegPath returns the ffmpeg binary to invoke
+func (v *Visualizer) ffmpegPath() string {
+	if v.config.FFmpegPath != "" {
+		return v.config.FFmpegPath
+	}
+	return "ffmpeg"
+}
+
+// ffprobePath returns the ffprobe binary to invoke
+func (v *Visualizer) ffprobePath() string {
+	if v.config.FFprobePath != "" {
+		return v.config.FFprobePath
+	}
+	return "ffprobe"
+}
+
+// hwEncoders maps hardware acceleration options to ffmpeg encoders
+var hwEncoders = map[string]string{
+	"nvenc":        "h264_nvenc",
+	"videotoolbox": "h264_videotoolbox",
+	"qsv":          "h264_qsv",
+}
+
+// resolveEncoder picks the video encoder, falling back to libx264 with a
+// warning when the requested hardware encoder isn't built into ffmpeg
+func (v *Visualizer) resolveEncoder() {
+	v.videoEncoder = orDefault(v.config.VideoCodec, "libx264")
+	if v.outputFormat() == "webm" && v.videoEncoder == "libx264" {
+		// WebM can't hold H.264, so the default encoder becomes VP9
+		v.videoEncoder = "libvpx-vp9"
+	}
 	
-	// First, get audio info using ffprobe
-	cmd := exec.Command("ffprobe",
-		"-v", "error",
-		"-show_entries", "format=duration",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		v.config.InputFile,
-	)
+	encoder, ok := hwEncoders[v.config.HWAccel]
+	if !ok {
+		return
+	}
 	
-	output, err := cmd.Output()
+	output, err := exec.Command(v.ffmpegPath(), "-hide_banner", "-encoders").Output()
+	if err != nil || !strings.Contains(string(output), " "+encoder+" ") {
+		fmt.Printf("Warning: %s encoder not available, falling back to libx264\n", encoder)
+		v.videoEncoder = "libx264"
+		return
+	}
+	
+	v.videoEncoder = encoder
+}
+
+// AudioSamples returns the decoded mono samples the spectrum is analysed
+// from, in -1..1, and their sample rate. They are loaded by CreateVideo,
+// RenderStill or CreateFrames; before that it returns nil and 0. The slice is
+// the visualizer's own, so treat it as read-only.
+func (v *Visualizer) AudioSamples() ([]float64, int) {
+	if v.audioData == nil {
+		return nil, 0
+	}
+	return v.audioData, v.sampleRate
+}
+
+// RenderStill renders the single frame shown at the given time in seconds
+func (v *Visualizer) RenderStill(atSeconds float64) (image.Image, error) {
+	if err := v.loadAudio(); err != nil {
+		return nil, fmt.Errorf("loading audio: %w", err)
+	}
+	
+	if err := v.precomputeSpectrum(); err != nil {
+		return nil, fmt.Errorf("computing spectrum: %w", err)
+	}
+	
+	if err := v.loadBackground(); err != nil {
+		return nil, err
+	}
+	if err := v.loadWatermark(); err != nil {
+		return nil, err
+	}
+	if err := v.loadCenterImage(); err != nil {
+		return nil, err
+	}
+	if err := v.loadTitleFont(); err != nil {
+		return nil, err
+	}
+	
+	frameIdx := int(atSeconds * float64(v.config.FPS))
+	if frameIdx >= v.totalFrames {
+		frameIdx = v.totalFrames - 1
+	}
+	if frameIdx < 0 {
+		frameIdx = 0
+	}
+	
+	return v.generateFrame(frameIdx).Image(), nil
+}
+
+// CreateFrames renders every frame to outDir as frame_%06d.png (.jpg with
+// JPEG frames) without encoding a video, using worker goroutines for the
+// parallel process types. outDir is created if needed and left in place.
+func (v *Visualizer) CreateFrames(outDir string) error {
+	if err := v.loadAudio(); err != nil {
+		return fmt.Errorf("loading audio: %w", err)
+	}
+	
+	fmt.Println("Pre-computing spectrum data...")
+	if err := v.precomputeSpectrum(); err != nil {
+		return fmt.Errorf("computing spectrum: %w", err)
+	}
+	
+	if err := v.loadBackground(); err != nil {
+		return err
+	}
+	if err := v.loadWatermark(); err != nil {
+		return err
+	}
+	if err := v.loadCenterImage(); err != nil {
+		return err
+	}
+	if err := v.loadTitleFont(); err != nil {
+		return err
+	}
+	
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("creating output dir: %w", err)
+	}
+	
+	fmt.Printf("Generating %d frames...\n", v.totalFrames)
+	switch v.config.ProcessType {
+	case "parallel", "parallel-pipe":
+		return v.writeFramesParallel(outDir)
+	}
+	return v.writeFramesSequential(outDir)
+}
+
+// loadBackground loads the background image (or the embedded cover art, or
+// paints the gradient) and fits it to the frame once, so each frame only has
+// to copy it
+func (v *Visualizer) loadBackground() error {
+	v.background = nil
+	if v.config.BackgroundImage == "" && v.coverArt != nil {
+		blur := v.config.BackgroundBlur
+		if blur == 0 {
+			blur = coverArtBlur
+		}
+		v.background = fitImage(v.coverArt, v.config.Width, v.config.Height, v.config.BackgroundFit, v.getBackgroundColor())
+		v.background = blurImage(v.background, int(math.Round(blur)))
+		return nil
+	}
+	if v.config.BackgroundImage == "" {
+		if v.config.BGGradientTop != "" && v.config.BGGradientBottom != "" {
+			top, err := parseHexColor(v.config.BGGradientTop)
+			if err != nil {
+				return fmt.Errorf("background gradient: %w", err)
+			}
+			bottom, err := parseHexColor(v.config.BGGradientBottom)
+			if err != nil {
+				return fmt.Errorf("background gradient: %w", err)
+			}
+			v.background = gradientImage(v.config.Width, v.config.Height, top, bottom)
+		}
+		return nil
+	}
+	
+	img, err := gg.LoadImage(v.config.BackgroundImage)
+	if err != nil {
+		return fmt.Errorf("loading background image: %w", err)
+	}
+	
+	v.background = fitImage(img, v.config.Width, v.config.Height, v.config.BackgroundFit, v.getBackgroundColor())
+	if radius := int(math.Round(v.config.BackgroundBlur)); radius > 0 {
+		v.background = blurImage(v.background, radius)
+	}
+	return nil
+}
+
+// watermarkMargin is the gap in pixels between the watermark and the frame edge
+const watermarkMargin = 16
+
+// loadWatermark loads the watermark once, scaling it down to fit inside the
+// frame and applying its opacity, so each frame only has to draw it
+func (v *Visualizer) loadWatermark() error {
+	v.watermark = nil
+	if v.config.WatermarkFile == "" {
+		return nil
+	}
+	
+	img, err := gg.LoadImage(v.config.WatermarkFile)
 	if err != nil {
-		return fmt.Errorf("getting audio duration: %w", err)
+		return fmt.Errorf("loading watermark: %w", err)
 	}
 	
-	// Parse duration
-	var fileDuration float64
-	fmt.Sscanf(string(output), "%f", &fileDuration)
+	// Scale down logos that don't fit inside the margins
+	bounds := img.Bounds()
+	maxWidth := float64(v.config.Width - 2*watermarkMargin)
+	maxHeight := float64(v.config.Height - 2*watermarkMargin)
+	scale := math.Min(1, math.Min(maxWidth/float64(bounds.Dx()), maxHeight/float64(bounds.Dy())))
+	width := int(math.Max(1, float64(bounds.Dx())*scale))
+	height := int(math.Max(1, float64(bounds.Dy())*scale))
+	if scale < 1 {
+		img = scaleImage(img, width, height)
+	}
 	
-	// Set duration
-	if v.config.Duration > 0 && v.config.Duration < fileDuration {
-		v.duration = v.config.Duration
-	} else {
-		v.duration = fileDuration
+	opacity := v.config.WatermarkOpacity
+	if opacity <= 0 || opacity > 1 {
+		opacity = 1
 	}
+	watermark := image.NewRGBA(image.Rect(0, 0, width, height))
+	mask := image.NewUniform(color.Alpha{uint8(opacity * 255)})
+	draw.DrawMask(watermark, watermark.Bounds(), img, img.Bounds().Min, mask, image.Point{}, draw.Over)
 	
-	v.totalFrames = int(v.duration * float64(v.config.FPS))
-	v.sampleRate = 22050 // Standard sample rate for analysis
+	v.watermark = watermark
+	return nil
+}
+
+// loadCenterImage loads CenterImage cropped to a square at the size the
+// center circle reaches at full pulse, so frames only ever scale it down
+func (v *Visualizer) loadCenterImage() error {
+	v.centerImage = nil
+	if v.config.CenterImage == "" {
+		return nil
+	}
 	
-	fmt.Printf("Audio duration: %.1f seconds, %d frames\n", v.duration, v.totalFrames)
+	img, err := gg.LoadImage(v.config.CenterImage)
+	if err != nil {
+		return fmt.Errorf("loading center image: %w", err)
+	}
 	
-	// Extract raw audio data using ffmpeg
-	// This is a simplified version - in production, use proper audio libraries
-	return v.extractAudioData()
+	radius, _ := v.circleRadii()
+	size := int(math.Ceil(2 * radius * (1 + v.config.CenterPulse)))
+	v.centerImage = fitImage(img, size, size, "cover", color.Transparent)
+	return nil
 }
 
-// extractAudioData extracts raw PCM data from the audio file
-func (v *Visualizer) extractAudioData() error {
-	// Create temp file for raw audio
-	tempFile := filepath.Join(os.TempDir(), "audio_temp.raw")
-	defer os.Remove(tempFile)
+// watermarkOrigin returns the top-left corner at which to draw the watermark
+func (v *Visualizer) watermarkOrigin() (int, int) {
+	bounds := v.watermark.Bounds()
+	left := watermarkMargin
+	top := watermarkMargin
+	right := v.config.Width - bounds.Dx() - watermarkMargin
+	bottom := v.config.Height - bounds.Dy() - watermarkMargin
+	
+	switch v.config.WatermarkPosition {
+	case "top-left":
+		return left, top
+	case "top-right":
+		return right, top
+	case "bottom-left":
+		return left, bottom
+	case "center":
+		return (v.config.Width - bounds.Dx()) / 2, (v.config.Height - bounds.Dy()) / 2
+	default: // "bottom-right"
+		return right, bottom
+	}
+}
+
+// loadTitleFont parses the title font once. Each frame creates its own face
+// from it, since faces cache glyphs and can't be shared between goroutines.
+func (v *Visualizer) loadTitleFont() error {
+	v.titleFont = nil
+	if v.config.TitleText == "" || v.config.TitleFont == "" {
+		return nil
+	}
+	
+	fontBytes, err := os.ReadFile(v.config.TitleFont)
+	if err != nil {
+		return fmt.Errorf("loading title font: %w", err)
+	}
+	v.titleFont, err = truetype.Parse(fontBytes)
+	if err != nil {
+		return fmt.Errorf("parsing title font: %w", err)
+	}
+	return nil
+}
+
+// coverArtBlur is the blur radius used for embedded cover art when
+// BackgroundBlur is not set
+const coverArtBlur = 10
+
+// extractCoverArt copies the artwork embedded in the input (if any) out with
+// ffmpeg and decodes it into coverArt; without artwork, BGColor is used
+func (v *Visualizer) extractCoverArt() {
+	v.coverArt = nil
+	
+	tempDir, err := os.MkdirTemp("", "spectrum_cover_*")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tempDir)
 	
-	// Convert to raw PCM using ffmpeg
-	cmd := exec.Command("ffmpeg",
+	// The copied stream may be PNG despite the name; decoding sniffs the format
+	coverFile := filepath.Join(tempDir, "cover.jpg")
+	cmd := exec.Command(v.ffmpegPath(),
 		"-i", v.config.InputFile,
-		"-f", "f32le",
-		"-acodec", "pcm_f32le",
-		"-ac", "1",
-		"-ar", fmt.Sprintf("%d", v.sampleRate),
-		"-t", fmt.Sprintf("%.2f", v.duration),
-		"-y", tempFile,
+		"-an",
+		"-vcodec", "copy",
+		"-frames:v", "1",
+		"-y", coverFile,
 	)
-	
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("converting audio: %w", err)
+		fmt.Println("No embedded artwork found, using background color")
+		return
 	}
 	
-	// Read the raw audio data
-	data, err := os.ReadFile(tempFile)
+	img, err := gg.LoadImage(coverFile)
 	if err != nil {
-		return fmt.Errorf("reading audio data: %w", err)
+		fmt.Printf("Warning: could not decode embedded artwork: %v\n", err)
+		return
 	}
 	
-	// Convert byte data to float32 samples
-	numSamples := len(data) / 4
-	v.audioData = make([]float64, numSamples)
+	fmt.Println("Using embedded artwork as background")
+	v.coverArt = img
+}
+
+// loadAudio loads the audio file and prepares it for processing
+func (v *Visualizer) loadAudio() error {
+	if v.inputReader != nil {
+		return v.loadAudioFromReader()
+	}
 	
-	for i := 0; i < numSamples; i++ {
-		// Convert 4 bytes to float32 (little endian)
-		idx := i * 4
-		bits := uint32(data[idx]) | uint32(data[idx+1])<<8 | uint32(data[idx+2])<<16 | uint32(data[idx+3])<<24
-		v.audioData[i] = float64(math.Float32frombits(bits))
+	// For now, we'll use ffmpeg to extract audio data
+	// In a production version, we'd use a proper audio library
+	if err := v.probeAudio(); err != nil {
+		return err
 	}
 	
-	return nil
+	fmt.Printf("Audio duration: %.1f seconds, %d frames\n", v.duration, v.totalFrames)
+	
+	if v.config.AutoBackground && v.config.BackgroundImage == "" {
+		v.extractCoverArt()
+	}
+	
+	// Reuse the samples an earlier render in a batch decoded with the same options
+	if v.prior != nil && v.prior.audioKey == v.audioKey() {
+		v.audioData = v.prior.audioData
+		return nil
+	}
+	
+	// Extract raw audio data using ffmpeg
+	// This is a simplified version - in production, use proper audio libraries
+	return v.extractAudioData()
 }
 
-// precomputeSpectrum pre-computes all spectrum data for the video
-func (v *Visualizer) precomputeSpectrum() error {
-	v.windowSize = 2048
-	hopLength := v.sampleRate / v.config.FPS
+// loadAudioFromReader decodes audio piped into ffmpeg from the input reader.
+// A stream can't be probed ahead of decoding, so the duration is that of the
+// decoded audio, capped at Config.Duration when set.
+func (v *Visualizer) loadAudioFromReader() error {
+	v.sampleRate = v.analysisRate()
+	v.duration = v.config.Duration
+	v.sourceLength = v.duration
 	
-	v.spectrumData = make([][]float64, v.totalFrames)
+	if err := v.extractAudioData(); err != nil {
+		return err
+	}
 	
-	// Process each frame
-	for frame := 0; frame < v.totalFrames; frame++ {
-		startIdx := frame * hopLength
-		endIdx := startIdx + v.windowSize
-		
-		if endIdx > len(v.audioData) {
-			// Pad with zeros if necessary
-			endIdx = len(v.audioData)
-		}
-		
-		// Get window of audio data
-		window := make([]float64, v.windowSize)
-		if startIdx < len(v.audioData) {
-			copy(window, v.audioData[startIdx:endIdx])
-		}
-		
-		// Apply window function (Hamming)
-		for i := range window {
-			window[i] *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(v.windowSize-1))
-		}
-		
-		// Compute FFT
-		fftData := fft.FFTReal(window)
-		
-		// Convert to magnitude spectrum
-		magnitudes := make([]float64, len(fftData)/2)
-		for i := range magnitudes {
-			magnitudes[i] = cmplx.Abs(fftData[i])
+	decoded := float64(len(v.audioData)) / float64(v.sampleRate)
+	if v.duration <= 0 || v.duration > decoded {
+		v.duration = decoded
+	}
+	if v.duration <= 0 {
+		return fmt.Errorf("no audio decoded from stream")
+	}
+	v.totalFrames = v.frameCount(v.duration)
+	if err := v.applyLoop(); err != nil {
+		return err
+	}
+	v.applyPadding()
+	if err := v.checkFrameCount(); err != nil {
+		return err
+	}
+	
+	fmt.Printf("Audio duration: %.1f seconds, %d frames\n", v.duration, v.totalFrames)
+	return nil
+}
+
+// analysisRate returns the sample rate audio is decoded at for analysis
+func (v *Visualizer) analysisRate() int {
+	if v.config.SampleRate > 0 {
+		return v.config.SampleRate
+	}
+	return 22050 // Standard sample rate for analysis
+}
+
+// probeAudio gets the audio duration using ffprobe and derives the frame count
+func (v *Visualizer) probeAudio() error {
+	fileDuration, err := v.probeDuration()
+	if err != nil {
+		return err
+	}
+	
+	// Set duration
+	if v.config.StartTime >= fileDuration {
+		return fmt.Errorf("start time %.2fs is past the end of the audio (%.2fs)", v.config.StartTime, fileDuration)
+	}
+	if len(v.config.Segments) > 0 {
+		v.duration = 0
+		for _, seg := range v.config.Segments {
+			if seg.Start+seg.Duration > fileDuration {
+				return fmt.Errorf("segment %.2f+%.2fs runs past the end of the audio (%.2fs)", seg.Start, seg.Duration, fileDuration)
+			}
+			v.duration += seg.Duration
+		}
+	} else if remaining := fileDuration - v.config.StartTime; v.config.Duration > 0 && v.config.Duration < remaining {
+		v.duration = v.config.Duration
+	} else {
+		v.duration = remaining
+	}
+	
+	v.totalFrames = v.frameCount(v.duration)
+	v.sampleRate = v.analysisRate()
+	
+	if err := v.applyLoop(); err != nil {
+		return err
+	}
+	v.applyPadding()
+	return v.checkFrameCount()
+}
+
+// checkFrameCount rejects a render too short to produce a single frame,
+// before any frames are rendered or ffmpeg is left looking for them
+func (v *Visualizer) checkFrameCount() error {
+	if v.totalFrames <= 0 {
+		return fmt.Errorf("computed zero frames from %.3fs of audio at %d FPS; increase the duration or FPS", v.duration, v.config.FPS)
+	}
+	return nil
+}
+
+// probeDuration returns the length of the input file in seconds, from the
+// header of a WAV file read natively or otherwise from ffprobe
+func (v *Visualizer) probeDuration() (float64, error) {
+	if info := v.nativeWAV(); info != nil {
+		return info.duration(), nil
+	}
+	
+	cmd := exec.Command(v.ffprobePath(),
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		v.config.InputFile,
+	)
+	
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("getting audio duration: %w", err)
+	}
+	
+	// Containers without a duration in their header report N/A, so measure
+	// those by decoding
+	if duration, ok := parseDuration(string(output)); ok {
+		return duration, nil
+	}
+	fmt.Printf("ffprobe reported no duration (%q), measuring by decoding\n", strings.TrimSpace(string(output)))
+	return v.measureDuration()
+}
+
+// parseDuration reads a duration in seconds printed by ffprobe, reporting
+// false for N/A, empty or non-positive values
+func parseDuration(output string) (float64, bool) {
+	duration, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil || duration <= 0 || math.IsInf(duration, 0) || math.IsNaN(duration) {
+		return 0, false
+	}
+	return duration, true
+}
+
+// measureDuration decodes the input's first audio stream to nowhere and
+// returns the length ffmpeg reached, for inputs ffprobe can't time
+func (v *Visualizer) measureDuration() (float64, error) {
+	cmd := exec.Command(v.ffmpegPath(),
+		"-hide_banner", "-nostats",
+		"-progress", "pipe:1",
+		"-i", v.config.InputFile,
+		"-map", "0:a:0",
+		"-f", "null", "-",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("measuring audio duration: %w", err)
+	}
+	
+	// -progress prints out_time_us=N after each update; the last one is the end
+	var duration float64
+	for _, line := range strings.Split(string(output), "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), "out_time_us="); found {
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us > 0 {
+				duration = float64(us) / 1e6
+			}
+		}
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("could not determine the duration of %s: ffprobe reported none and decoding found no audio", v.config.InputFile)
+	}
+	return duration, nil
+}
+
+// frameCount returns the number of frames covering duration seconds,
+// rounding up so the last partial frame is rendered: with -shortest, a video
+// that stops short of the audio cuts off its final moments. The tolerance
+// keeps durations that are a whole number of frames from gaining one to
+// floating-point error.
+func (v *Visualizer) frameCount(duration float64) int {
+	return int(math.Ceil(duration*float64(v.config.FPS) - 1e-6))
+}
+
+// applyLoop records the length of the source audio in seconds and frames,
+// then stretches the render to Config.LoopToDuration when set. Frames past
+// the source wrap around to its start, and the output audio loops to match.
+func (v *Visualizer) applyLoop() error {
+	v.sourceLength = v.duration
+	v.sourceFrames = v.totalFrames
+	if v.config.LoopToDuration <= 0 {
+		return nil
+	}
+	
+	if v.config.LoopToDuration < v.duration {
+		return fmt.Errorf("loop duration %.2fs is shorter than the audio (%.2fs); use Duration to trim instead", v.config.LoopToDuration, v.duration)
+	}
+	loops := v.config.LoopToDuration / v.duration
+	if loops-math.Floor(loops) > 0.01 {
+		fmt.Printf("Warning: %.2fs is %.2f loops of the %.2fs audio; the last loop is cut short\n", v.config.LoopToDuration, loops, v.duration)
+	}
+	
+	v.duration = v.config.LoopToDuration
+	v.totalFrames = v.frameCount(v.duration)
+	return nil
+}
+
+// applyPadding handles a Config.Duration longer than the source audio: with
+// PadToDuration the render is extended to it, and the frames past the audio
+// show no spectrum while the output audio is padded with silence; otherwise
+// the render keeps the audio's length and says so.
+func (v *Visualizer) applyPadding() {
+	if v.config.Duration-v.sourceLength < 1/float64(v.config.FPS) || len(v.config.Segments) > 0 || v.config.LoopToDuration > 0 {
+		return
+	}
+	
+	if !v.config.PadToDuration {
+		fmt.Printf("Warning: the audio is only %.2fs, shorter than the %.2fs duration; the video ends with it (set PadToDuration to pad with silence)\n", v.sourceLength, v.config.Duration)
+		return
+	}
+	
+	v.duration = v.config.Duration
+	v.totalFrames = v.frameCount(v.duration)
+}
+
+// padded reports whether the render runs past the end of the source audio
+// because of PadToDuration
+func (v *Visualizer) padded() bool {
+	return v.config.PadToDuration && v.totalFrames > v.sourceFrames
+}
+
+// checkInputVideo makes sure the input has a video stream to overlay onto
+func (v *Visualizer) checkInputVideo() error {
+	cmd := exec.Command(v.ffprobePath(),
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_type",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		v.config.InputFile,
+	)
+	
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("probing input video: %w", err)
+	}
+	if strings.TrimSpace(string(output)) != "video" {
+		return fmt.Errorf("overlay on input requires a video input, %s has no video stream", v.config.InputFile)
+	}
+	return nil
+}
+
+// checkFrameLimit guards against renders whose frame count would exhaust
+// memory or temp disk. Above FrameLimit, modes that write a PNG per frame are
+// refused; streaming modes only warn since they keep no frames on disk.
+func (v *Visualizer) checkFrameLimit() error {
+	if v.config.FrameLimit <= 0 || v.totalFrames <= v.config.FrameLimit {
+		return nil
+	}
+	
+	switch v.config.ProcessType {
+	case "pipe", "parallel-pipe":
+		fmt.Printf("Warning: rendering %d frames exceeds the frame limit of %d\n", v.totalFrames, v.config.FrameLimit)
+		return nil
+	}
+	return fmt.Errorf("%d frames exceeds the frame limit of %d; use the pipe or parallel-pipe process type, lower the FPS or raise FrameLimit", v.totalFrames, v.config.FrameLimit)
+}
+
+// estimateResources approximates the memory and disk a render needs once the
+// audio has been probed
+func (v *Visualizer) estimateResources() *ResourceEstimate {
+	frameBytes := int64(v.config.Width) * int64(v.config.Height) * 4
+	
+	estimate := &ResourceEstimate{
+		Duration:       v.duration,
+		FrameCount:     v.totalFrames,
+		AudioMemory:    int64(v.sourceLength*float64(v.sampleRate)) * 8,
+		SpectrumMemory: int64(v.totalFrames) * (int64(v.config.BarCount)*8 + 24),
+		FrameMemory:    frameBytes,
+	}
+	
+	switch v.config.ProcessType {
+	case "pipe", "parallel-pipe":
+	default:
+		// Spectrum frames are mostly flat color, so PNGs compress to roughly
+		// a sixteenth of the raw frame size and JPEGs to about half that
+		estimate.TempDiskUsage = int64(v.totalFrames) * frameBytes / 16
+		if v.frameExt() == "jpg" {
+			estimate.TempDiskUsage /= 2
+		}
+	}
+	
+	return estimate
+}
+
+// Rough single-core drawing throughput in pixels per second, for frames
+// streamed raw to ffmpeg and for frames also encoded as PNG files
+const (
+	renderPixelRate = 150e6
+	framePixelRate  = 50e6
+)
+
+// estimateRender extends estimateResources with the frame size and a rough
+// render time once the audio has been probed
+func (v *Visualizer) estimateRender() *RenderEstimate {
+	rate := framePixelRate
+	workers := 1
+	switch v.config.ProcessType {
+	case "pipe":
+		rate = renderPixelRate
+	case "parallel-pipe":
+		rate, workers = renderPixelRate, v.workers()
+	case "parallel":
+		workers = v.workers()
+	}
+	
+	pixels := float64(v.totalFrames) * float64(v.config.Width) * float64(v.config.Height)
+	seconds := pixels / (rate * float64(workers))
+	
+	return &RenderEstimate{
+		ResourceEstimate: *v.estimateResources(),
+		Width:            v.config.Width,
+		Height:           v.config.Height,
+		RenderTime:       time.Duration(seconds * float64(time.Second)),
+	}
+}
+
+// segmentFilter returns an ffmpeg filter graph that trims each configured
+// segment out of the given input's audio and concatenates them as [label]
+func (v *Visualizer) segmentFilter(input int, label string) string {
+	var parts []string
+	var inputs string
+	for i, seg := range v.config.Segments {
+		parts = append(parts, fmt.Sprintf("[%d:a]atrim=start=%.3f:duration=%.3f,asetpts=PTS-STARTPTS[s%d]", input, seg.Start, seg.Duration, i))
+		inputs += fmt.Sprintf("[s%d]", i)
+	}
+	parts = append(parts, fmt.Sprintf("%sconcat=n=%d:v=0:a=1[%s]", inputs, len(v.config.Segments), label))
+	return strings.Join(parts, ";")
+}
+
+// loopRate is the sample rate looped output audio is resampled to, so the
+// number of samples in one pass of the source is known
+const loopRate = 48000
+
+// loopFilter returns an ffmpeg filter graph that trims the given input to the
+// analysed audio (its segments, or the first sourceLength seconds after the
+// start offset) and repeats it forever; -shortest ends it with the video
+func (v *Visualizer) loopFilter(input int, label string) string {
+	source := fmt.Sprintf("[%d:a]atrim=duration=%.3f,asetpts=PTS-STARTPTS[src]", input, v.sourceLength)
+	if len(v.config.Segments) > 0 {
+		source = v.segmentFilter(input, "src")
+	}
+	size := int(math.Round(v.sourceLength * loopRate))
+	return fmt.Sprintf("%s;[src]aresample=%d,aloop=loop=-1:size=%d[%s]", source, loopRate, size, label)
+}
+
+// surroundLayouts lists the channel order of the surround layouts that get a
+// weighted downmix; other layouts use ffmpeg's default -ac 1 downmix
+var surroundLayouts = map[string][]string{
+	"5.0":       {"FL", "FR", "FC", "BL", "BR"},
+	"5.0(side)": {"FL", "FR", "FC", "SL", "SR"},
+	"5.1":       {"FL", "FR", "FC", "LFE", "BL", "BR"},
+	"5.1(side)": {"FL", "FR", "FC", "LFE", "SL", "SR"},
+	"6.1":       {"FL", "FR", "FC", "LFE", "BC", "SL", "SR"},
+	"7.1":       {"FL", "FR", "FC", "LFE", "BL", "BR", "SL", "SR"},
+}
+
+// downmixFilter returns a pan filter folding a surround layout to mono the
+// way an ITU stereo downmix would (center and surrounds at -3dB into each
+// side, LFE dropped), or "" for layouts left to ffmpeg
+func downmixFilter(layout string) string {
+	channels, ok := surroundLayouts[layout]
+	if !ok {
+		return ""
+	}
+	
+	var terms []string
+	for _, ch := range channels {
+		var weight float64
+		switch ch {
+		case "FL", "FR":
+			weight = 0.5
+		case "FC":
+			weight = 0.707
+		case "LFE":
+			continue
+		default: // Surround and back channels
+			weight = 0.354
+		}
+		terms = append(terms, fmt.Sprintf("%.3f*%s", weight, ch))
+	}
+	return "pan=mono|c0=" + strings.Join(terms, "+")
+}
+
+// probeChannelLayout returns the channel layout of the first audio stream,
+// such as "stereo" or "5.1(side)", or "" if it can't be determined
+func (v *Visualizer) probeChannelLayout() string {
+	output, err := exec.Command(v.ffprobePath(),
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "stream=channel_layout",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		v.config.InputFile,
+	).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// extractAudioData extracts raw PCM data from the audio file
+func (v *Visualizer) extractAudioData() error {
+	// Plain PCM WAV files are read directly, which is faster than ffmpeg
+	if info := v.nativeWAV(); info != nil {
+		return v.decodeWAV(info)
+	}
+	
+	// Create a uniquely named temp file for raw audio
+	raw, err := os.CreateTemp("", "spectrum_audio_*.raw")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	raw.Close()
+	tempFile := raw.Name()
+	defer os.Remove(tempFile)
+	
+	// Weight surround channels properly instead of ffmpeg's default downmix
+	var downmix string
+	if v.config.SurroundDownmix && v.inputReader == nil {
+		layout := v.probeChannelLayout()
+		if downmix = downmixFilter(layout); downmix != "" {
+			fmt.Printf("Downmixing %s surround to mono\n", layout)
+		}
+	}
+	
+	// Convert to raw PCM using ffmpeg, stitching segments together if set
+	args := []string{"-i", v.config.InputFile}
+	if v.inputReader != nil {
+		args = []string{"-i", "pipe:0"}
+	}
+	if v.config.StartTime > 0 {
+		args = append([]string{"-ss", fmt.Sprintf("%.3f", v.config.StartTime)}, args...)
+	}
+	if len(v.config.Segments) > 0 {
+		filter, label := v.segmentFilter(0, "seg"), "[seg]"
+		if downmix != "" {
+			filter, label = filter+";[seg]"+downmix+"[mono]", "[mono]"
+		}
+		args = append(args,
+			"-filter_complex", filter,
+			"-map", label,
+		)
+	} else {
+		if v.sourceLength > 0 {
+			args = append(args, "-t", fmt.Sprintf("%.2f", v.sourceLength))
+		}
+		if downmix != "" {
+			args = append(args, "-af", downmix)
+		}
+	}
+	format := "f32le"
+	if v.config.SampleFormat == "s16" {
+		format = "s16le"
+	}
+	args = append(args,
+		"-f", format,
+		"-acodec", "pcm_"+format,
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", v.sampleRate),
+		"-y", tempFile,
+	)
+	
+	// A stream can only be read once, so keep its audio for muxing as well
+	if v.inputReader != nil {
+		args = append(args, "-map", "0:a:0", "-c:a", "copy")
+		if v.sourceLength > 0 {
+			args = append(args, "-t", fmt.Sprintf("%.2f", v.sourceLength))
+		}
+		args = append(args, "-y", v.streamCopy)
+	}
+	
+	cmd := exec.Command(v.ffmpegPath(), args...)
+	cmd.Stdin = v.inputReader
+	
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("converting audio: %w", err)
+	}
+	
+	// Read the raw audio data
+	data, err := os.ReadFile(tempFile)
+	if err != nil {
+		return fmt.Errorf("reading audio data: %w", err)
+	}
+	
+	if format == "s16le" {
+		// Convert 2 bytes to int16 (little endian), scaled to -1..1
+		v.audioData = make([]float64, len(data)/2)
+		for i := range v.audioData {
+			v.audioData[i] = float64(int16(uint16(data[i*2])|uint16(data[i*2+1])<<8)) / 32768
+		}
+		return nil
+	}
+	
+	// Convert byte data to float32 samples
+	numSamples := len(data) / 4
+	v.audioData = make([]float64, numSamples)
+	
+	for i := 0; i < numSamples; i++ {
+		// Convert 4 bytes to float32 (little endian)
+		idx := i * 4
+		bits := uint32(data[idx]) | uint32(data[idx+1])<<8 | uint32(data[idx+2])<<16 | uint32(data[idx+3])<<24
+		v.audioData[i] = float64(math.Float32frombits(bits))
+	}
+	
+	return nil
+}
+
+// precomputeSpectrum pre-computes all spectrum data for the video
+func (v *Visualizer) precomputeSpectrum() error {
+	v.windowSize = 2048
+	frameHop := v.sampleRate / v.config.FPS
+	v.binWeights = v.weightingCurve(v.windowSize / 2)
+	v.prepareBands()
+	
+	// Reuse the spectra of an earlier render in a batch with the same analysis
+	if v.prior != nil && v.prior.spectrumKey == v.spectrumKey() {
+		v.spectrumData, v.peaks = v.prior.spectrumData, v.prior.peaks
+		return nil
+	}
+	
+	// A looped render reuses the spectra of the source frames
+	frames := v.totalFrames
+	if v.sourceFrames > 0 && v.sourceFrames < frames {
+		frames = v.sourceFrames
+	}
+	v.spectrumData = make([][]float64, frames)
+	
+	if v.config.HopLength <= 0 || v.config.HopLength == frameHop {
+		// One analysis window per video frame
+		for frame := 0; frame < frames; frame++ {
+			v.spectrumData[frame] = v.analyzeWindow(frame * frameHop)
+		}
+	} else {
+		// Analyse at the configured stride, then interpolate between the
+		// two windows around each video frame's position
+		hop := float64(v.config.HopLength)
+		analyzed := make([][]float64, int(float64(frames*frameHop)/hop)+2)
+		for i := range analyzed {
+			analyzed[i] = v.analyzeWindow(i * v.config.HopLength)
+		}
+		
+		for frame := 0; frame < frames; frame++ {
+			pos := float64(frame*frameHop) / hop
+			i := min(int(pos), len(analyzed)-2)
+			t := pos - float64(i)
+			
+			bins := make([]float64, v.config.BarCount)
+			for b := range bins {
+				bins[b] = analyzed[i][b]*(1-t) + analyzed[i+1][b]*t
+			}
+			v.spectrumData[frame] = bins
+		}
+	}
+	
+	if v.config.Normalize {
+		v.normalizeSpectrum()
+	} else if v.config.AGC {
+		v.applyAGC()
+	}
+	
+	for frame := range v.spectrumData {
+		// Blend with the previous frame; higher smoothing makes bars more sticky
+		if frame > 0 && v.config.Smoothing > 0 {
+			smoothing := v.config.Smoothing
+			for i := range v.spectrumData[frame] {
+				v.spectrumData[frame][i] = v.spectrumData[frame][i]*(1-smoothing) + v.spectrumData[frame-1][i]*smoothing
+			}
+		}
+	}
+	
+	if v.config.PeakHold {
+		v.computePeaks()
+	} else {
+		v.peaks = nil
+	}
+	
+	return nil
+}
+
+// prepareBands records each bar's center frequency for the draw functions,
+// and the fixed color of each bar inside an EQ band. It needs the analysis
+// sample rate, so it runs once the audio is loaded.
+func (v *Visualizer) prepareBands() {
+	edges := v.bandEdges()
+	v.barFreqs = make([]float64, v.config.BarCount)
+	for i := range v.barFreqs {
+		v.barFreqs[i] = math.Sqrt(edges[i] * edges[i+1])
+	}
+	
+	v.eqColors = nil
+	if len(v.config.EQBands) == 0 {
+		return
+	}
+	v.eqColors = make([]color.Color, v.config.BarCount)
+	for i, freq := range v.barFreqs {
+		for _, band := range v.config.EQBands {
+			if freq >= band.MinFreq && freq < band.MaxFreq {
+				v.eqColors[i], _ = parseHexColor(band.Color)
+				break
+			}
+		}
+	}
+}
+
+// normalizeSpectrum scales every bar level by the same factor so the
+// loudest across the whole track is 1, then applies the noise gate
+func (v *Visualizer) normalizeSpectrum() {
+	var peak float64
+	for _, bins := range v.spectrumData {
+		for _, level := range bins {
+			peak = math.Max(peak, level)
+		}
+	}
+	if peak == 0 {
+		return
+	}
+	
+	for _, bins := range v.spectrumData {
+		for i := range bins {
+			bins[i] /= peak
+			if bins[i] < v.config.NoiseGate {
+				bins[i] = 0
+			}
+		}
+	}
+}
+
+// agcFloor is the lowest reference level AGC divides by, so near silence is
+// not boosted into full-height hiss
+const agcFloor = 0.05
+
+// applyAGC rescales each frame against the loudest level in the trailing
+// AGCWindow seconds, then applies the noise gate. The window's maximum is kept
+// as a running queue of frame indices with decreasing peaks.
+func (v *Visualizer) applyAGC() {
+	window := v.config.AGCWindow
+	if window <= 0 {
+		window = 3
+	}
+	span := max(int(window*float64(v.config.FPS)), 1)
+	
+	peaks := make([]float64, len(v.spectrumData))
+	var queue []int
+	for frame, bins := range v.spectrumData {
+		for _, level := range bins {
+			peaks[frame] = math.Max(peaks[frame], level)
+		}
+		
+		// Drop frames that left the window or can no longer be its maximum
+		for len(queue) > 0 && queue[0] <= frame-span {
+			queue = queue[1:]
+		}
+		for len(queue) > 0 && peaks[queue[len(queue)-1]] <= peaks[frame] {
+			queue = queue[:len(queue)-1]
+		}
+		queue = append(queue, frame)
+		
+		reference := math.Max(peaks[queue[0]], agcFloor)
+		for i := range bins {
+			bins[i] = math.Min(bins[i]/reference, 1)
+			if bins[i] < v.config.NoiseGate {
+				bins[i] = 0
+			}
+		}
+	}
+}
+
+// rescaled reports whether levels are rescaled after analysis, in which case
+// binFrequencies leaves clipping and the noise gate to the rescaling pass
+func (v *Visualizer) rescaled() bool {
+	return v.config.Normalize || v.config.AGC
+}
+
+// analyzeWindow returns the binned spectrum of the Hamming-windowed audio
+// starting at sample start, zero-padded past the end of the audio
+func (v *Visualizer) analyzeWindow(start int) []float64 {
+	end := start + v.windowSize
+	if end > len(v.audioData) {
+		// Pad with zeros if necessary
+		end = len(v.audioData)
+	}
+	
+	// Get window of audio data
+	window := make([]float64, v.windowSize)
+	if start < len(v.audioData) {
+		copy(window, v.audioData[start:end])
+	}
+	
+	// Apply window function (Hamming)
+	for i := range window {
+		window[i] *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(v.windowSize-1))
+	}
+	
+	// Compute FFT
+	fftData := fft.FFTReal(window)
+	
+	// Convert to magnitude spectrum
+	magnitudes := make([]float64, len(fftData)/2)
+	for i := range magnitudes {
+		magnitudes[i] = cmplx.Abs(fftData[i])
+	}
+	
+	// Create frequency bins (logarithmic scale)
+	return v.binFrequencies(magnitudes)
+}
+
+// computePeaks builds the peak-hold level of every bar for each frame. The
+// peaks are recomputed from scratch on each render so a reused visualizer
+// starts clean, and storing them per frame keeps parallel rendering safe.
+func (v *Visualizer) computePeaks() {
+	v.peaks = make([][]float64, len(v.spectrumData))
+	
+	current := make([]float64, v.config.BarCount)
+	for frame, magnitudes := range v.spectrumData {
+		for i, magnitude := range magnitudes {
+			current[i] -= v.config.PeakDecay
+			if magnitude > current[i] {
+				current[i] = magnitude
+			}
+			if current[i] < 0 {
+				current[i] = 0
+			}
+		}
+		v.peaks[frame] = append([]float64(nil), current...)
+	}
+}
+
+// frameSamples returns the raw audio samples analyzed for a frame, which may
+// be shorter than the window (or empty) at the end of the audio
+func (v *Visualizer) frameSamples(frameIdx int) []float64 {
+	if v.config.FPS <= 0 {
+		return nil
+	}
+	start := frameIdx * (v.sampleRate / v.config.FPS)
+	if start >= len(v.audioData) {
+		return nil
+	}
+	end := start + v.windowSize
+	if end > len(v.audioData) {
+		end = len(v.audioData)
+	}
+	return v.audioData[start:end]
+}
+
+// binFrequencies bins the frequency data into the desired number of bars
+func (v *Visualizer) binFrequencies(magnitudes []float64) []float64 {
+	bins := make([]float64, v.config.BarCount)
+	freqBins := v.bandEdges()
+	
+	// Map frequency bins to FFT bins
+	fftBinWidth := float64(v.sampleRate) / float64(len(magnitudes)*2)
+	
+	// Apply the loudness weighting to each FFT bin
+	if len(v.binWeights) == len(magnitudes) {
+		weighted := make([]float64, len(magnitudes))
+		for j, m := range magnitudes {
+			weighted[j] = m * v.binWeights[j]
+		}
+		magnitudes = weighted
+	}
+	
+	for i := 0; i < v.config.BarCount; i++ {
+		// Combine the magnitudes in this frequency range
+		var sum, peak, count float64
+		if v.config.FractionalBins {
+			sum, peak, count = weightedBinRange(magnitudes, freqBins[i]/fftBinWidth, freqBins[i+1]/fftBinWidth)
+		} else {
+			startBin := int(freqBins[i] / fftBinWidth)
+			endBin := int(freqBins[i+1] / fftBinWidth)
+			
+			if startBin >= len(magnitudes) {
+				startBin = len(magnitudes) - 1
+			}
+			if endBin >= len(magnitudes) {
+				endBin = len(magnitudes) - 1
+			}
+			
+			for j := startBin; j <= endBin && j < len(magnitudes); j++ {
+				sum += magnitudes[j]
+				peak = math.Max(peak, magnitudes[j])
+				count++
+			}
+		}
+		
+		if count > 0 {
+			switch v.config.BinAggregation {
+			case "max":
+				bins[i] = peak
+			case "sum":
+				bins[i] = sum
+			default: // "average"
+				bins[i] = sum / count
+			}
+		}
+		
+		// Normalize magnitude (FFT magnitudes can be very large)
+		// First divide by window size to get proper scale
+		bins[i] = bins[i] / float64(v.windowSize)
+		
+		// Map to the 0-1 display range
+		if bins[i] > 0 {
+			bins[i] = v.scaleAmplitude(bins[i])
+			if v.config.Sensitivity > 0 {
+				bins[i] *= v.config.Sensitivity
+			}
+			
+			// Ensure within 0-1 range; normalizing rescales instead of clipping
+			if bins[i] < 0 {
+				bins[i] = 0
+			} else if bins[i] > 1 && !v.rescaled() {
+				bins[i] = 1
+			}
+		}
+		
+		// Silence anything under the noise gate, after normalizing if enabled
+		if bins[i] < v.config.NoiseGate && !v.rescaled() {
+			bins[i] = 0
+		}
+	}
+	
+	return bins
+}
+
+// bandEdges returns the BarCount+1 frequencies in Hz bounding the bars, from
+// 80Hz to 8000Hz on the configured scale, reaching proportionally higher at
+// higher sample rates
+func (v *Visualizer) bandEdges() []float64 {
+	minFreq := 80.0
+	maxFreq := 8000.0 * float64(v.sampleRate) / 22050
+	
+	edges := make([]float64, v.config.BarCount+1)
+	for i := 0; i <= v.config.BarCount; i++ {
+		t := float64(i) / float64(v.config.BarCount)
+		switch v.config.FreqScale {
+		case "linear":
+			edges[i] = minFreq + (maxFreq-minFreq)*t
+		case "mel":
+			minMel, maxMel := hzToMel(minFreq), hzToMel(maxFreq)
+			edges[i] = melToHz(minMel + (maxMel-minMel)*t)
+		default: // "log"
+			edges[i] = minFreq * math.Pow(maxFreq/minFreq, t)
+		}
+	}
+	return edges
+}
+
+// weightingCurve returns the amplitude gain of each of n FFT bins for the
+// configured weighting, or nil when magnitudes are left flat
+func (v *Visualizer) weightingCurve(n int) []float64 {
+	if v.config.Weighting != "a-weight" {
+		return nil
+	}
+	
+	binWidth := float64(v.sampleRate) / float64(n*2)
+	gains := make([]float64, n)
+	for j := range gains {
+		gains[j] = aWeight(float64(j) * binWidth)
+	}
+	return gains
+}
+
+// aWeight returns the IEC 61672 A-weighting amplitude gain at hz, normalised
+// to 1 at 1kHz
+func aWeight(hz float64) float64 {
+	f2 := hz * hz
+	ra := 12194 * 12194 * f2 * f2 /
+		((f2 + 20.6*20.6) * math.Sqrt((f2+107.7*107.7)*(f2+737.9*737.9)) * (f2 + 12194*12194))
+	// +2.0 dB puts 1kHz at unity gain
+	return ra * math.Pow(10, 2.0/20)
+}
+
+// hzToMel converts a frequency in Hz to mels
+func hzToMel(hz float64) float64 {
+	return 2595 * math.Log10(1+hz/700)
+}
+
+// melToHz converts mels back to a frequency in Hz
+func melToHz(mel float64) float64 {
+	return 700 * (math.Pow(10, mel/2595) - 1)
+}
+
+// weightedBinRange combines the FFT bins overlapping the fractional bin range
+// [lo, hi), weighting each bin by how much of it the range covers, where bin j
+// spans [j, j+1). It returns the weighted sum, the peak of the touched bins
+// and the total weight.
+func weightedBinRange(magnitudes []float64, lo, hi float64) (sum, peak, weight float64) {
+	n := float64(len(magnitudes))
+	if n == 0 {
+		return 0, 0, 0
+	}
+	lo = math.Min(math.Max(lo, 0), n-1)
+	hi = math.Min(hi, n)
+	
+	// A range narrower than a bin (or clamped at the top) takes the bin it sits in
+	if hi <= lo {
+		m := magnitudes[int(lo)]
+		return m, m, 1
+	}
+	
+	for j := int(lo); float64(j) < hi; j++ {
+		overlap := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+		if overlap <= 0 {
+			continue
+		}
+		sum += magnitudes[j] * overlap
+		peak = math.Max(peak, magnitudes[j])
+		weight += overlap
+	}
+	return sum, peak, weight
+}
+
+// scaleAmplitude maps a normalized magnitude according to the amplitude scale
+func (v *Visualizer) scaleAmplitude(magnitude float64) float64 {
+	switch v.config.AmplitudeScale {
+	case "linear":
+		return magnitude
+	case "db":
+		floor := v.config.DBFloor
+		if floor == 0 {
+			floor = -60
+		}
+		db := 20 * math.Log10(magnitude)
+		if db < floor {
+			db = floor
+		}
+		return (db - floor) / -floor
+	default: // "log"
+		multiplier, divisor := v.config.LogScaleMultiplier, v.config.LogScaleDivisor
+		if multiplier == 0 {
+			multiplier = 1000
+		}
+		if divisor == 0 {
+			divisor = 3
+		}
+		return math.Log10(magnitude*multiplier+1) / divisor
+	}
+}
+
+// createVideoSequential creates the video frame by frame
+func (v *Visualizer) createVideoSequential() error {
+	// Create the directory for frames (temporary unless caching)
+	tempDir, cleanup, err := v.frameDir()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	
+	if err := v.writeFramesSequential(tempDir); err != nil {
+		return err
+	}
+	
+	if err := v.markFramesComplete(); err != nil {
+		return err
+	}
+	
+	// Create video using ffmpeg
+	return v.assembleVideo(tempDir)
+}
+
+// writeFramesSequential renders every frame to dir as frame_%06d.png (or
+// .jpg), one at a time
+func (v *Visualizer) writeFramesSequential(dir string) error {
+	progress := newFrameProgress(v.totalFrames)
+	for i := 0; i < v.totalFrames; i++ {
+		if i%progressInterval == 0 {
+			fmt.Println(progress.line("Processing", i))
+		}
+		
+		filename := v.framePath(dir, i)
+		if err := v.saveFrame(filename, i); err != nil {
+			return fmt.Errorf("saving frame %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// progressInterval is the number of frames between progress lines, and so
+// between samples of the frame rate behind their ETA
+const progressInterval = 30
+
+// frameProgress estimates the time left in a render from a moving average of
+// the frame rate, sampled only when a progress line is printed
+type frameProgress struct {
+	total    int
+	last     time.Time
+	lastDone int
+	rate     float64 // Frames per second, 0 until the first sample
+}
+
+func newFrameProgress(total int) *frameProgress {
+	return &frameProgress{total: total, last: time.Now()}
+}
+
+// line folds the frames done since the last sample into the average rate and
+// formats a progress line, with an ETA once the rate is known
+func (p *frameProgress) line(verb string, done int) string {
+	now := time.Now()
+	if elapsed := now.Sub(p.last).Seconds(); elapsed > 0 && done > p.lastDone {
+		rate := float64(done-p.lastDone) / elapsed
+		if p.rate == 0 {
+			p.rate = rate
+		} else {
+			// Weight recent samples so the ETA follows the render speeding up
+			// or slowing down without jumping on a single slow frame
+			p.rate = 0.7*p.rate + 0.3*rate
+		}
+		p.last, p.lastDone = now, done
+	}
+	
+	percent := float64(done) / float64(p.total) * 100
+	if p.rate == 0 {
+		return fmt.Sprintf("%s frame %d/%d (%.1f%%)", verb, done, p.total, percent)
+	}
+	eta := time.Duration(float64(p.total-done) / p.rate * float64(time.Second))
+	return fmt.Sprintf("%s frame %d/%d (%.1f%%, ETA %s)", verb, done, p.total, percent, formatETA(eta))
+}
+
+// formatETA formats a duration as mm:ss, or h:mm:ss from an hour up
+func formatETA(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+// createVideoPipe streams raw RGBA frames straight into ffmpeg's stdin,
+// avoiding the temporary PNG directory entirely
+func (v *Visualizer) createVideoPipe() error {
+	cmd, stdin, err := v.startPipe()
+	if err != nil {
+		return err
+	}
+	
+	progress := newFrameProgress(v.totalFrames)
+	for i := 0; i < v.totalFrames; i++ {
+		if i%progressInterval == 0 {
+			fmt.Println(progress.line("Processing", i))
 		}
 		
-		// Create frequency bins (logarithmic scale)
-		v.spectrumData[frame] = v.binFrequencies(magnitudes)
-		
-		// Apply smoothing with more responsive factor
-		if frame > 0 {
-			for i := range v.spectrumData[frame] {
-				v.spectrumData[frame][i] = v.spectrumData[frame][i]*0.85 + v.spectrumData[frame-1][i]*0.15
-			}
+		if err := writeRawFrame(stdin, v.generateFrame(i)); err != nil {
+			stdin.Close()
+			cmd.Wait()
+			return fmt.Errorf("writing frame %d: %w", i, err)
 		}
 	}
 	
-	return nil
+	if err := stdin.Close(); err != nil {
+		return fmt.Errorf("closing ffmpeg stdin: %w", err)
+	}
+	
+	return cmd.Wait()
 }
 
-// binFrequencies bins the frequency data into the desired number of bars
-func (v *Visualizer) binFrequencies(magnitudes []float64) []float64 {
-	bins := make([]float64, v.config.BarCount)
+// workers returns how many goroutines the parallel methods render with:
+// Workers when set, otherwise one per CPU
+func (v *Visualizer) workers() int {
+	if v.config.Workers > 0 {
+		return v.config.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// createVideoParallelPipe renders frames on Workers goroutines and streams
+// them to ffmpeg's stdin in order. Workers may finish out of order, so
+// completed frames wait in a reorder buffer until every earlier frame has been
+// written. At most ReorderWindow frames are in flight at once; each one holds
+// Width*Height*4 bytes, so a larger window keeps workers busier at the cost
+// of memory (about 3.7 MB per frame at 1280x720).
+func (v *Visualizer) createVideoParallelPipe() error {
+	cmd, stdin, err := v.startPipe()
+	if err != nil {
+		return err
+	}
 	
-	// Create logarithmic frequency bins from 80Hz to 8000Hz
-	minFreq := 80.0
-	maxFreq := 8000.0
+	numWorkers := v.workers()
+	window := v.config.ReorderWindow
+	if window <= 0 {
+		window = numWorkers * 2
+	}
+	fmt.Printf("Using %d workers for parallel processing (reorder window %d frames)\n", numWorkers, window)
+	progress := newFrameProgress(v.totalFrames)
 	
-	freqBins := make([]float64, v.config.BarCount+1)
-	for i := 0; i <= v.config.BarCount; i++ {
-		freqBins[i] = minFreq * math.Pow(maxFreq/minFreq, float64(i)/float64(v.config.BarCount))
+	type result struct {
+		frameIdx int
+		pixels   []byte
 	}
 	
-	// Map frequency bins to FFT bins
-	fftBinWidth := float64(v.sampleRate) / float64(len(magnitudes)*2)
+	jobs := make(chan int)
+	results := make(chan result, window)
+	slots := make(chan struct{}, window)
 	
-	for i := 0; i < v.config.BarCount; i++ {
-		startBin := int(freqBins[i] / fftBinWidth)
-		endBin := int(freqBins[i+1] / fftBinWidth)
-		
-		if startBin >= len(magnitudes) {
-			startBin = len(magnitudes) - 1
-		}
-		if endBin >= len(magnitudes) {
-			endBin = len(magnitudes) - 1
-		}
-		
-		// Average the magnitudes in this frequency range
-		sum := 0.0
-		count := 0
-		for j := startBin; j <= endBin && j < len(magnitudes); j++ {
-			sum += magnitudes[j]
-			count++
-		}
-		
-		if count > 0 {
-			bins[i] = sum / float64(count)
+	// Hand out frames in order, never more than window ahead of the writer
+	go func() {
+		for i := 0; i < v.totalFrames; i++ {
+			slots <- struct{}{}
+			jobs <- i
 		}
-		
-		// Normalize magnitude (FFT magnitudes can be very large)
-		// First divide by window size to get proper scale
-		bins[i] = bins[i] / float64(v.windowSize)
-		
-		// Apply logarithmic scaling for better visual response
-		if bins[i] > 0 {
-			// Use log scale with adjustable sensitivity
-			bins[i] = math.Log10(bins[i]*1000 + 1) / 3.0
+		close(jobs)
+	}()
+	
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				img := v.generateFrame(i).Image().(*image.RGBA)
+				results <- result{frameIdx: i, pixels: img.Pix}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	
+	// Write frames in sequence; after a write error keep draining so the
+	// workers can finish and exit
+	pending := make(map[int][]byte, window)
+	next := 0
+	var writeErr error
+	for r := range results {
+		pending[r.frameIdx] = r.pixels
+		for pixels, ok := pending[next]; ok; pixels, ok = pending[next] {
+			if writeErr == nil {
+				if _, err := stdin.Write(pixels); err != nil {
+					writeErr = fmt.Errorf("writing frame %d: %w", next, err)
+				}
+			}
+			delete(pending, next)
+			next++
+			<-slots
 			
-			// Ensure within 0-1 range
-			if bins[i] < 0 {
-				bins[i] = 0
-			} else if bins[i] > 1 {
-				bins[i] = 1
+			if next%progressInterval == 0 || next == v.totalFrames {
+				fmt.Println(progress.line("Processed", next))
 			}
 		}
 	}
 	
-	return bins
+	if writeErr != nil {
+		stdin.Close()
+		cmd.Wait()
+		return writeErr
+	}
+	
+	if err := stdin.Close(); err != nil {
+		return fmt.Errorf("closing ffmpeg stdin: %w", err)
+	}
+	
+	return cmd.Wait()
 }
 
-// createVideoSequential creates the video frame by frame
-func (v *Visualizer) createVideoSequential() error {
-	// Create temporary directory for frames
-	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("spectrum_frames_%d", time.Now().Unix()))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		return fmt.Errorf("creating temp dir: %w", err)
+// startPipe starts ffmpeg reading raw RGBA frames from stdin
+func (v *Visualizer) startPipe() (*exec.Cmd, io.WriteCloser, error) {
+	args := []string{
+		"-f", "rawvideo",
+		"-pix_fmt", "rgba",
+		"-s", fmt.Sprintf("%dx%d", v.config.Width, v.config.Height),
+		"-framerate", fmt.Sprintf("%d", v.config.FPS),
+		"-i", "pipe:0",
 	}
-	defer os.RemoveAll(tempDir)
+	cmd := exec.Command(v.ffmpegPath(), append(args, v.outputArgs()...)...)
+	cmd.Stdout = v.encoderStdout()
+	cmd.Stderr = os.Stderr
 	
-	// Generate frames
-	for i := 0; i < v.totalFrames; i++ {
-		if i%30 == 0 {
-			fmt.Printf("Processing frame %d/%d (%.1f%%)\n", i, v.totalFrames, float64(i)/float64(v.totalFrames)*100)
-		}
-		
-		frame := v.generateFrame(i)
-		filename := filepath.Join(tempDir, fmt.Sprintf("frame_%06d.png", i))
-		if err := frame.SavePNG(filename); err != nil {
-			return fmt.Errorf("saving frame %d: %w", i, err)
-		}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening ffmpeg stdin: %w", err)
 	}
 	
-	// Create video using ffmpeg
-	return v.assembleVideo(tempDir)
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
+	
+	return cmd, stdin, nil
+}
+
+// writeRawFrame writes the RGBA pixels of a frame to w
+func writeRawFrame(w io.Writer, dc *gg.Context) error {
+	img, ok := dc.Image().(*image.RGBA)
+	if !ok {
+		return fmt.Errorf("unexpected frame image type %T", dc.Image())
+	}
+	_, err := w.Write(img.Pix)
+	return err
 }
 
 // createVideoParallel creates the video using parallel processing
 func (v *Visualizer) createVideoParallel() error {
-	// Create temporary directory for frames
-	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("spectrum_frames_%d", time.Now().Unix()))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		return fmt.Errorf("creating temp dir: %w", err)
+	// Create the directory for frames (temporary unless caching)
+	tempDir, cleanup, err := v.frameDir()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	
+	if err := v.writeFramesParallel(tempDir); err != nil {
+		return err
+	}
+	
+	if err := v.markFramesComplete(); err != nil {
+		return err
 	}
-	defer os.RemoveAll(tempDir)
 	
+	// Create video using ffmpeg
+	return v.assembleVideo(tempDir)
+}
+
+// writeFramesParallel renders every frame to dir as frame_%06d.png (or .jpg)
+// using a pool of Workers goroutines
+func (v *Visualizer) writeFramesParallel(dir string) error {
 	// Use worker pool
-	numWorkers := runtime.NumCPU()
-	fmt.Printf("Using %d CPU cores for parallel processing\n", numWorkers)
+	numWorkers := v.workers()
+	fmt.Printf("Using %d workers for parallel processing\n", numWorkers)
 	
 	type job struct {
 		frameIdx int
@@ -324,7 +1929,8 @@ func (v *Visualizer) createVideoParallel() error {
 	jobs := make(chan job, v.totalFrames)
 	errors := make(chan error, numWorkers)
 	var completed int64
-	var printMu sync.Mutex
+	var printMu sync.Mutex // Also guards progress
+	progress := newFrameProgress(v.totalFrames)
 	
 	// Start workers
 	var wg sync.WaitGroup
@@ -333,16 +1939,15 @@ func (v *Visualizer) createVideoParallel() error {
 		go func() {
 			defer wg.Done()
 			for j := range jobs {
-				frame := v.generateFrame(j.frameIdx)
-				if err := frame.SavePNG(j.filename); err != nil {
+				if err := v.saveFrame(j.filename, j.frameIdx); err != nil {
 					errors <- fmt.Errorf("saving frame %d: %w", j.frameIdx, err)
 					return
 				}
 
 				done := atomic.AddInt64(&completed, 1)
-				if done%30 == 0 || done == int64(v.totalFrames) {
+				if done%progressInterval == 0 || done == int64(v.totalFrames) {
 					printMu.Lock()
-					fmt.Printf("Processed frame %d/%d (%.1f%%)\n", done, v.totalFrames, float64(done)/float64(v.totalFrames)*100)
+					fmt.Println(progress.line("Processed", int(done)))
 					printMu.Unlock()
 				}
 			}
@@ -353,7 +1958,7 @@ func (v *Visualizer) createVideoParallel() error {
 	for i := 0; i < v.totalFrames; i++ {
 		jobs <- job{
 			frameIdx: i,
-			filename: filepath.Join(tempDir, fmt.Sprintf("frame_%06d.png", i)),
+			filename: v.framePath(dir, i),
 		}
 	}
 	close(jobs)
@@ -368,9 +1973,7 @@ func (v *Visualizer) createVideoParallel() error {
 			return err
 		}
 	}
-	
-	// Create video using ffmpeg
-	return v.assembleVideo(tempDir)
+	return nil
 }
 
 // assembleVideo uses ffmpeg to create the final video
@@ -378,67 +1981,511 @@ func (v *Visualizer) assembleVideo(frameDir string) error {
 	fmt.Println("Assembling video with audio...")
 	
 	// Create video from frames and add audio
-	cmd := exec.Command("ffmpeg",
+	args := []string{
 		"-framerate", fmt.Sprintf("%d", v.config.FPS),
-		"-i", filepath.Join(frameDir, "frame_%06d.png"),
-		"-i", v.config.InputFile,
-		"-c:v", "libx264",
-		"-preset", "ultrafast",
-		"-pix_fmt", "yuv420p",
-		"-c:a", "aac",
-		"-b:a", "192k",
-		"-shortest",
-		"-y", v.config.OutputFile,
-	)
+		"-i", filepath.Join(frameDir, "frame_%06d."+v.frameExt()),
+	}
+	cmd := exec.Command(v.ffmpegPath(), append(args, v.outputArgs()...)...)
 	
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = v.encoderStdout()
 	cmd.Stderr = os.Stderr
 	
 	return cmd.Run()
 }
 
+// outputArgs returns the ffmpeg arguments that follow the frame input:
+// the audio input, encoder settings and output file. GIF and WebP outputs
+// are silent animations, so they skip the audio input.
+func (v *Visualizer) outputArgs() []string {
+	// -n also stops ffmpeg replacing a file created since claimOutput checked
+	overwrite := "-y"
+	if v.config.NoOverwrite && v.output() != pipeOutput {
+		overwrite = "-n"
+	}
+	output := []string{overwrite, v.output()}
+	if v.config.OutputFormat != "" {
+		// Name the muxer, since the file extension may not match it
+		output = append([]string{"-f", v.config.OutputFormat}, output...)
+	}
+	if v.output() == pipeOutput && v.outputFormat() == "mp4" {
+		// Put the index up front and write fragments, as a pipe can't seek back
+		output = append([]string{"-movflags", "frag_keyframe+empty_moov"}, output...)
+	}
+	
+	switch v.outputFormat() {
+	case "gif":
+		// The gif muxer repeats N extra times, with 0 meaning loop forever and
+		// -1 play once
+		loop := v.config.LoopCount - 1
+		switch v.config.LoopCount {
+		case 0:
+			loop = 0
+		case 1:
+			loop = -1
+		}
+		return append([]string{
+			"-filter_complex", "[0:v]split[a][b];[a]palettegen[p];[b][p]paletteuse",
+			"-loop", fmt.Sprintf("%d", loop),
+		}, output...)
+	case "webp":
+		return append([]string{
+			"-c:v", "libwebp",
+			"-loop", fmt.Sprintf("%d", v.config.LoopCount),
+			"-an",
+		}, output...)
+	}
+	
+	args := []string{"-i", v.audioInput()}
+	if v.config.StartTime > 0 && v.streamCopy == "" {
+		// Start the output audio at the same offset as the analysis; a
+		// stream copy was already cut there while decoding
+		args = append([]string{"-ss", fmt.Sprintf("%.3f", v.config.StartTime)}, args...)
+	}
+	if v.config.OverlayOnInput {
+		// Input 0 is the transparent spectrum, input 1 the original video
+		args = append(args,
+			"-filter_complex", "[1:v][0:v]overlay=(W-w)/2:H-h:shortest=1[vout]",
+			"-map", "[vout]",
+			"-map", "1:a?",
+		)
+	} else if v.config.LoopToDuration > 0 {
+		args = append(args,
+			"-filter_complex", v.loopFilter(1, "aout"),
+			"-map", "0:v",
+			"-map", "[aout]",
+		)
+	} else if len(v.config.Segments) > 0 {
+		args = append(args,
+			"-filter_complex", v.segmentFilter(1, "aout"),
+			"-map", "0:v",
+			"-map", "[aout]",
+		)
+	} else if v.padded() {
+		// Silence after the audio ends; -shortest then stops at the video
+		args = append(args, "-af", "apad")
+	}
+	
+	encoder := orDefault(v.videoEncoder, orDefault(v.config.VideoCodec, "libx264"))
+	args = append(args, "-c:v", encoder)
+	
+	// Hardware encoders use their own preset names and quality flags
+	quality := fmt.Sprintf("%d", v.config.VideoCRF)
+	switch encoder {
+	case "h264_nvenc":
+		if v.config.VideoCRF > 0 {
+			args = append(args, "-cq", quality)
+		}
+	case "h264_qsv":
+		if v.config.VideoCRF > 0 {
+			args = append(args, "-global_quality", quality)
+		}
+	case "h264_videotoolbox":
+		// VideoToolbox has no presets, and its -q:v runs 1-100 with higher
+		// better, so map the CRF onto it (the default 23 becomes 54)
+		if v.config.VideoCRF > 0 {
+			args = append(args, "-q:v", fmt.Sprintf("%d", max(1, 100-2*v.config.VideoCRF)))
+		}
+	case "libvpx-vp9":
+		// libvpx trades speed for quality with -cpu-used rather than presets,
+		// and only treats -crf as constant quality with a zero bitrate
+		args = append(args, "-deadline", "good", "-cpu-used", vpxSpeed(v.config.VideoPreset), "-row-mt", "1")
+		if v.config.VideoCRF > 0 {
+			args = append(args, "-crf", quality, "-b:v", "0")
+		}
+	default:
+		args = append(args, "-preset", orDefault(v.config.VideoPreset, "ultrafast"))
+		if v.config.VideoCRF > 0 {
+			args = append(args, "-crf", quality)
+		}
+	}
+	
+	audioCodec := "aac"
+	if v.outputFormat() == "webm" {
+		audioCodec = "libopus"
+	}
+	
+	args = append(args, "-pix_fmt", orDefault(v.config.PixelFormat, "yuv420p"))
+	if v.config.Profile != "" {
+		args = append(args, "-profile:v", v.config.Profile)
+	}
+	if v.config.Level != "" {
+		args = append(args, "-level:v", v.config.Level)
+	}
+	
+	args = append(args,
+		"-c:a", audioCodec,
+		"-b:a", orDefault(v.config.AudioBitrate, "192k"),
+		"-shortest",
+	)
+	return append(args, output...)
+}
+
+// vpxSpeed maps an x264 preset name to a libvpx -cpu-used speed, from 8 for
+// ultrafast down to 0 for the slowest presets
+func vpxSpeed(preset string) string {
+	for i, p := range GetVideoPresets() {
+		if p == preset {
+			return fmt.Sprintf("%d", max(0, 8-i))
+		}
+	}
+	return "8"
+}
+
+// pipeOutput is the OutputFile that makes ffmpeg write the encoded video to
+// its stdout, which is copied to the visualizer's outputWriter
+const pipeOutput = "pipe:1"
+
+// encoderStdout returns where the encoding ffmpeg's stdout goes: the output
+// writer when streaming the video, otherwise the terminal
+func (v *Visualizer) encoderStdout() io.Writer {
+	if v.outputWriter != nil {
+		return v.outputWriter
+	}
+	return os.Stdout
+}
+
+// outputFormat returns the container the output is written as
+func (v *Visualizer) outputFormat() string {
+	return resolveOutputFormat(v.config.OutputFormat, v.config.OutputFile)
+}
+
+// resolveOutputFormat returns format when set, otherwise the output file's
+// extension in lowercase without the dot
+func resolveOutputFormat(format, outputFile string) string {
+	if format != "" {
+		return format
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(outputFile)), ".")
+}
+
+// audioInput returns the file whose audio is muxed into the output: the input
+// file, or the copy of the stream saved while decoding reader input
+func (v *Visualizer) audioInput() string {
+	if v.streamCopy != "" {
+		return v.streamCopy
+	}
+	return v.config.InputFile
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// lineCap returns the gg line cap for the configured LineCap
+func (v *Visualizer) lineCap() gg.LineCap {
+	switch v.config.LineCap {
+	case "butt":
+		return gg.LineCapButt
+	case "square":
+		return gg.LineCapSquare
+	}
+	return gg.LineCapRound
+}
+
+// frameState holds the data that varies from one rendered frame to the next.
+// generateFrame builds a fresh one per call and only reads from the
+// Visualizer, so parallel workers share no mutable state. Anything a frame
+// needs to compute or cache while drawing belongs here, never on Visualizer.
+type frameState struct {
+	index      int       // Video frame being rendered
+	specIdx    int       // Spectrum frame shown, after VisualSpeed
+	magnitudes []float64 // Bar levels for the spectrum frame, after SilenceThreshold
+	peaks      []float64 // Peak-hold levels, or nil when PeakHold is off
+	hueShift   float64   // Degrees ColorCycleSpeed has rotated the hues by
+	rotation   float64   // Radians RotationSpeed has turned the circular types by
+}
+
+// newFrameState gathers the spectrum data video frame frameIdx draws from,
+// and the hue and rotation it is drawn with
+func (v *Visualizer) newFrameState(frameIdx int) *frameState {
+	f := &frameState{index: frameIdx, specIdx: v.spectrumFrame(frameIdx)}
+	
+	if f.specIdx < len(v.spectrumData) {
+		f.magnitudes = v.silenceGate(v.spectrumData[f.specIdx])
+	} else {
+		f.magnitudes = make([]float64, v.config.BarCount)
+	}
+	if f.specIdx < len(v.peaks) {
+		f.peaks = v.peaks[f.specIdx]
+	}
+	
+	if v.config.ColorCycleSpeed != 0 || v.config.RotationSpeed != 0 {
+		elapsed := float64(frameIdx) / float64(v.config.FPS)
+		f.hueShift = math.Mod(v.config.ColorCycleSpeed*elapsed, 360)
+		f.rotation = math.Mod(v.config.RotationSpeed*elapsed, 360) * math.Pi / 180
+	}
+	
+	return f
+}
+
+// spectrumFrame maps a video frame to the spectrum frame it shows. Looped
+// renders wrap around to the start of the source. VisualSpeed below 1 repeats
+// spectra (slow motion) and above 1 skips them, while the audio always plays
+// at normal speed; fast visuals that run out of spectrum wrap around to the
+// start rather than leave the rest of the video silent.
+func (v *Visualizer) spectrumFrame(frameIdx int) int {
+	if v.config.LoopToDuration > 0 && v.sourceFrames > 0 {
+		frameIdx %= v.sourceFrames
+	}
+	if v.config.VisualSpeed <= 0 || v.config.VisualSpeed == 1 {
+		return frameIdx
+	}
+	
+	specIdx := int(float64(frameIdx) * v.config.VisualSpeed)
+	if v.config.VisualSpeed > 1 && len(v.spectrumData) > 0 {
+		specIdx %= len(v.spectrumData)
+	}
+	return specIdx
+}
+
 // generateFrame generates a single frame of the visualization
 func (v *Visualizer) generateFrame(frameIdx int) *gg.Context {
 	dc := gg.NewContext(v.config.Width, v.config.Height)
+	dc.SetLineCap(v.lineCap())
 	
-	// Set background color
-	bgColor := v.getBackgroundColor()
-	dc.SetColor(bgColor)
-	dc.Clear()
+	// Set background image or color
+	switch {
+	case v.config.OverlayOnInput:
+		// Leave the frame transparent so the input video shows through
+	case v.background != nil:
+		dc.DrawImage(v.background, 0, 0)
+	default:
+		bgColor := v.getBackgroundColor()
+		dc.SetColor(bgColor)
+		dc.Clear()
+	}
+	
+	f := v.newFrameState(frameIdx)
 	
-	// Get spectrum data for this frame
-	var magnitudes []float64
-	if frameIdx < len(v.spectrumData) {
-		magnitudes = v.spectrumData[frameIdx]
+	if v.config.Shadow {
+		// Draw on a transparent layer so its shape can be cast as the shadow
+		layer := gg.NewContext(v.config.Width, v.config.Height)
+		layer.SetLineCap(v.lineCap())
+		v.drawVisualization(layer, f)
+		
+		offset := int(math.Round(v.config.ShadowOffset))
+		dc.DrawImage(v.shadowImage(layer.Image().(*image.RGBA)), offset, offset)
+		dc.DrawImage(layer.Image(), 0, 0)
 	} else {
-		magnitudes = make([]float64, v.config.BarCount)
+		v.drawVisualization(dc, f)
+	}
+	
+	if v.config.ShowFreqAxis && (v.config.VizType == "" || v.config.VizType == "bars") {
+		v.drawFreqAxis(dc)
+	}
+	
+	// Draw the watermark and title above the visualization
+	if v.watermark != nil {
+		x, y := v.watermarkOrigin()
+		dc.DrawImage(v.watermark, x, y)
+	}
+	if v.config.TitleText != "" {
+		v.drawTitle(dc)
 	}
 	
+	return dc
+}
+
+// drawVisualization draws the configured visualization type for a frame,
+// with the center image of the circular types
+func (v *Visualizer) drawVisualization(dc *gg.Context, f *frameState) {
 	// Draw visualization based on type
 	switch v.config.VizType {
 	case "circular":
-		v.drawCircular(dc, magnitudes)
+		v.drawCircular(dc, f)
 	case "wave":
-		v.drawWave(dc, magnitudes)
+		v.drawWave(dc, f)
 	case "radial":
-		v.drawRadial(dc, magnitudes)
+		v.drawRadial(dc, f)
 	case "line":
-		v.drawLine(dc, magnitudes)
+		v.drawLine(dc, f)
 	case "dots":
-		v.drawDots(dc, magnitudes)
+		v.drawDots(dc, f)
 	case "mirror":
-		v.drawMirror(dc, magnitudes)
+		v.drawMirror(dc, f)
 	case "spiral":
-		v.drawSpiral(dc, magnitudes)
+		v.drawSpiral(dc, f)
+	case "spectrogram":
+		v.drawSpectrogram(dc, f)
+	case "oscilloscope":
+		v.drawOscilloscope(dc, f)
+	case "circular-wave":
+		v.drawCircularWave(dc, f)
+	case "vu-meter":
+		v.drawVUMeter(dc, f)
+	case "hbars":
+		v.drawHBars(dc, f)
+	case "area":
+		v.drawArea(dc, f)
+	case "butterfly":
+		v.drawButterfly(dc, f)
 	default: // "bars"
-		v.drawBars(dc, magnitudes)
+		v.drawBars(dc, f)
 	}
 	
-	return dc
+	if v.centerImage != nil && (v.config.VizType == "circular" || v.config.VizType == "circular-wave") {
+		v.drawCenterImage(dc, f.magnitudes)
+	}
+}
+
+// silenceGate returns magnitudes with every level below SilenceThreshold set
+// to 0, so all visualizations draw quiet bands the same way: at their resting
+// size in the scheme's silent color
+func (v *Visualizer) silenceGate(magnitudes []float64) []float64 {
+	if v.config.SilenceThreshold <= 0 {
+		return magnitudes
+	}
+	
+	gated := make([]float64, len(magnitudes))
+	for i, magnitude := range magnitudes {
+		if magnitude >= v.config.SilenceThreshold {
+			gated[i] = magnitude
+		}
+	}
+	return gated
+}
+
+// shadowOpacity is the opacity of the shadow under fully opaque parts of the
+// visualization, before blurring
+const shadowOpacity = 0.75
+
+// shadowImage fills the visualization's shape with ShadowColor, following
+// its transparency, and blurs it by ShadowBlur
+func (v *Visualizer) shadowImage(layer *image.RGBA) image.Image {
+	shadow := image.NewRGBA(layer.Rect)
+	r, g, b, _ := v.shadowColor.RGBA()
+	for i := 3; i < len(layer.Pix); i += 4 {
+		if layer.Pix[i] == 0 {
+			continue
+		}
+		// Premultiplied, so every channel scales with the alpha
+		alpha := float64(layer.Pix[i]) / 255 * shadowOpacity
+		shadow.Pix[i-3] = uint8(float64(r>>8) * alpha)
+		shadow.Pix[i-2] = uint8(float64(g>>8) * alpha)
+		shadow.Pix[i-1] = uint8(float64(b>>8) * alpha)
+		shadow.Pix[i] = uint8(alpha * 255)
+	}
+	
+	if radius := int(math.Round(v.config.ShadowBlur)); radius > 0 {
+		return blurImage(shadow, radius)
+	}
+	return shadow
+}
+
+// scaleImage resizes an image to the given dimensions
+func scaleImage(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	dc := gg.NewContext(width, height)
+	dc.Scale(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+	dc.DrawImage(img, 0, 0)
+	return dc.Image()
+}
+
+// fitImage scales an image onto a width x height canvas according to fit
+// ("fill", "contain" or "cover"), letterboxing contained images with bg
+func fitImage(img image.Image, width, height int, fit string, bg color.Color) image.Image {
+	bounds := img.Bounds()
+	scaleX := float64(width) / float64(bounds.Dx())
+	scaleY := float64(height) / float64(bounds.Dy())
+	
+	switch fit {
+	case "fill":
+	case "contain":
+		scaleX = math.Min(scaleX, scaleY)
+		scaleY = scaleX
+	default: // "cover"
+		scaleX = math.Max(scaleX, scaleY)
+		scaleY = scaleX
+	}
+	
+	dc := gg.NewContext(width, height)
+	dc.SetColor(bg)
+	dc.Clear()
+	dc.Translate(float64(width)/2, float64(height)/2)
+	dc.Scale(scaleX, scaleY)
+	dc.DrawImageAnchored(img, 0, 0, 0.5, 0.5)
+	return dc.Image()
+}
+
+// blurImage approximates a gaussian blur of the given radius with three
+// passes of a separable box blur
+func blurImage(img image.Image, radius int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(src.Bounds())
+	
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(dst, src, radius, true)
+		boxBlur(src, dst, radius, false)
+	}
+	return src
+}
+
+// boxBlur averages each pixel of src with its neighbors within radius along
+// one axis into dst, clamping at the edges
+func boxBlur(dst, src *image.RGBA, radius int, horizontal bool) {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	lines, length := height, width
+	if !horizontal {
+		lines, length = width, height
+	}
+	offset := func(line, i int) int {
+		i = max(0, min(length-1, i))
+		if horizontal {
+			return line*src.Stride + i*4
+		}
+		return i*src.Stride + line*4
+	}
+	window := 2*radius + 1
+	
+	for line := 0; line < lines; line++ {
+		var sum [4]int
+		for i := -radius; i <= radius; i++ {
+			o := offset(line, i)
+			for c := 0; c < 4; c++ {
+				sum[c] += int(src.Pix[o+c])
+			}
+		}
+		for i := 0; i < length; i++ {
+			o := offset(line, i)
+			for c := 0; c < 4; c++ {
+				dst.Pix[o+c] = uint8(sum[c] / window)
+			}
+			
+			// Slide the window one pixel forward
+			in, out := offset(line, i+radius+1), offset(line, i-radius)
+			for c := 0; c < 4; c++ {
+				sum[c] += int(src.Pix[in+c]) - int(src.Pix[out+c])
+			}
+		}
+	}
+}
+
+// gradientImage paints a vertical gradient from top to bottom
+func gradientImage(width, height int, top, bottom color.Color) image.Image {
+	dc := gg.NewContext(width, height)
+	gradient := gg.NewLinearGradient(0, 0, 0, float64(height))
+	gradient.AddColorStop(0, top)
+	gradient.AddColorStop(1, bottom)
+	dc.SetFillStyle(gradient)
+	dc.DrawRectangle(0, 0, float64(width), float64(height))
+	dc.Fill()
+	return dc.Image()
 }
 
 // Color helper functions
 func (v *Visualizer) getBackgroundColor() color.Color {
+	if strings.HasPrefix(v.config.BgColor, "#") {
+		if c, err := parseHexColor(v.config.BgColor); err == nil {
+			return c
+		}
+	}
+	
 	switch v.config.BgColor {
 	case "blue":
 		return color.RGBA{0, 0, 255, 255}
@@ -455,7 +2502,48 @@ func (v *Visualizer) getBackgroundColor() color.Color {
 	}
 }
 
-func (v *Visualizer) getColor(magnitude float64) color.Color {
+// getBarColor returns the color of bar index at the given magnitude, with the
+// hue rotated by hueShift degrees. Bars in an EQ band always take its color.
+// In frequency mode the hue follows the bar's band across HueSpan degrees from
+// BaseHue and the magnitude only sets the brightness; otherwise it is getColor.
+func (v *Visualizer) getBarColor(index int, magnitude, hueShift float64) color.Color {
+	if index < len(v.eqColors) && v.eqColors[index] != nil {
+		return v.eqColors[index]
+	}
+	if v.config.ColorMode != "frequency" {
+		return v.getColor(magnitude, hueShift)
+	}
+	if v.accentColor != nil && magnitude > v.config.AccentThreshold {
+		return v.accentColor
+	}
+	
+	// 0 is treated as the full wheel so literal configs still get a spread
+	span := v.config.HueSpan
+	if span <= 0 {
+		span = 360
+	}
+	
+	// Sample each band at its center so a full wheel doesn't repeat the first hue
+	position := (float64(index) + 0.5) / float64(v.config.BarCount)
+	hue := math.Mod(v.config.BaseHue+position*span+hueShift+360, 360)
+	brightness := 0.6 + 0.4*math.Max(0, math.Min(1, magnitude))
+	
+	return hsvToRGB(hue/360, 1, brightness)
+}
+
+// getColor returns the color scheme's color for a magnitude, with the hue
+// rotated by hueShift degrees for ColorCycleSpeed
+func (v *Visualizer) getColor(magnitude, hueShift float64) color.Color {
+	if v.accentColor != nil && magnitude > v.config.AccentThreshold {
+		return v.accentColor
+	}
+	if hueShift != 0 {
+		return rotateHue(v.getSchemeColor(magnitude), hueShift)
+	}
+	return v.getSchemeColor(magnitude)
+}
+
+func (v *Visualizer) getSchemeColor(magnitude float64) color.Color {
 	switch v.config.ColorScheme {
 	case "fire":
 		return v.getFireColor(magnitude)
@@ -784,6 +2872,39 @@ func (v *Visualizer) getMatrixColor(magnitude float64) color.Color {
 	}
 }
 
+// parseHexColor parses a "#RRGGBB" or "#RGB" color (the # is optional)
+func parseHexColor(hex string) (color.Color, error) {
+	s := strings.TrimPrefix(hex, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return nil, fmt.Errorf("%q is not a #RRGGBB color", hex)
+	}
+	
+	value, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a #RRGGBB color", hex)
+	}
+	return color.RGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 255}, nil
+}
+
+// shadeColor lightens a color towards white (amount > 0) or darkens it
+// towards black (amount < 0), with amount in the range -1..1
+func shadeColor(c color.Color, amount float64) color.Color {
+	r, g, b, a := c.RGBA()
+	shade := func(ch uint32) uint8 {
+		val := float64(ch >> 8)
+		if amount > 0 {
+			val += (255 - val) * amount
+		} else {
+			val += val * amount
+		}
+		return uint8(val)
+	}
+	return color.RGBA{shade(r), shade(g), shade(b), uint8(a >> 8)}
+}
+
 // HSV to RGB conversion helper
 func hsvToRGB(h, s, v float64) color.Color {
 	c := v * s
@@ -813,3 +2934,32 @@ func hsvToRGB(h, s, v float64) color.Color {
 		255,
 	}
 }
+
+// rotateHue turns a color's hue by the given degrees, keeping its saturation,
+// brightness and alpha, so grays and white are unchanged
+func rotateHue(c color.Color, degrees float64) color.Color {
+	r32, g32, b32, a32 := c.RGBA()
+	r, g, b := float64(r32)/0xffff, float64(g32)/0xffff, float64(b32)/0xffff
+	
+	high := math.Max(r, math.Max(g, b))
+	low := math.Min(r, math.Min(g, b))
+	delta := high - low
+	if delta == 0 {
+		return c
+	}
+	
+	var hue float64
+	switch high {
+	case r:
+		hue = math.Mod((g-b)/delta+6, 6)
+	case g:
+		hue = (b-r)/delta + 2
+	default:
+		hue = (r-g)/delta + 4
+	}
+	hue = math.Mod(hue*60+degrees+360, 360)
+	
+	rotated := hsvToRGB(hue/360, delta/high, high).(color.RGBA)
+	rotated.A = uint8(a32 >> 8)
+	return rotated
+}
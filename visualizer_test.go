@@ -0,0 +1,542 @@
+package audiospectrum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestWAV writes seconds of a full-scale sine at freq Hz as 16-bit mono
+// PCM at rate Hz and returns its path
+func writeTestWAV(t *testing.T, seconds float64, rate int, freq float64) string {
+	t.Helper()
+
+	n := int(seconds * float64(rate))
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+n*2))
+	buf.WriteString("WAVEfmt ")
+	for _, field := range []any{
+		uint32(16), uint16(wavFormatPCM), uint16(1),
+		uint32(rate), uint32(rate * 2), uint16(2), uint16(16),
+	} {
+		binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(n*2))
+	for i := 0; i < n; i++ {
+		sample := math.Sin(2 * math.Pi * freq * float64(i) / float64(rate))
+		binary.Write(&buf, binary.LittleEndian, int16(sample*32767))
+	}
+
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testConfig returns a config rendering one second of a 440 Hz tone at the
+// smallest allowed size, which is read natively so needs no ffmpeg
+func testConfig(t *testing.T) *Config {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.InputFile = writeTestWAV(t, 1, 44100, 440)
+	config.OutputFile = filepath.Join(t.TempDir(), "out.mp4")
+	config.Width = 320
+	config.Height = 240
+	config.FPS = 10
+	config.BarCount = 16
+	return config
+}
+
+// fakeFFmpeg is a stand-in for ffmpeg that writes its input to the output
+// file, its last argument: raw frames from stdin, or the frame images named
+// by the first -i concatenated in order
+const fakeFFmpeg = `#!/bin/sh
+[ "$1" = "-version" ] && exit 0
+for arg; do out=$arg; done
+while [ $# -gt 0 ]; do
+	[ "$1" = "-i" ] && [ -z "$input" ] && input=$2
+	shift
+done
+if [ "$input" = "pipe:0" ]; then
+	cat > "$out"
+else
+	cat "$(dirname "$input")"/frame_* > "$out"
+fi
+`
+
+// fakeTool writes script to an executable file and returns its path, for
+// use as FFmpegPath or FFprobePath
+func fakeTool(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+
+	path := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// renderRaw renders every frame of config one after another and returns
+// their RGBA pixels back to back, as the pipe process types send them
+func renderRaw(t *testing.T, config *Config) []byte {
+	t.Helper()
+
+	v := NewVisualizer(newVisualizerConfig(config))
+	if err := v.loadAudio(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.precomputeSpectrum(); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw []byte
+	for i := 0; i < v.totalFrames; i++ {
+		raw = append(raw, v.generateFrame(i).Image().(*image.RGBA).Pix...)
+	}
+	return raw
+}
+
+// TestParallelRenderMatchesFast renders with the parallel process types and
+// checks every frame matches the fast, sequential render. Run it with -race
+// to catch workers sharing per-frame state.
+func TestParallelRenderMatchesFast(t *testing.T) {
+	config := testConfig(t)
+	config.PeakHold = true
+	config.ColorCycleSpeed = 30
+
+	fastDir := t.TempDir()
+	if err := NewVisualizer(newVisualizerConfig(config)).CreateFrames(fastDir); err != nil {
+		t.Fatal(err)
+	}
+	fastFrames, err := filepath.Glob(filepath.Join(fastDir, "frame_*.png"))
+	if err != nil || len(fastFrames) == 0 {
+		t.Fatalf("fast render wrote no frames (%v)", err)
+	}
+
+	t.Run("parallel", func(t *testing.T) {
+		parallel := *config
+		parallel.ProcessType = ProcessTypeParallel
+		parallel.Workers = 4
+
+		dir := t.TempDir()
+		if err := NewVisualizer(newVisualizerConfig(&parallel)).CreateFrames(dir); err != nil {
+			t.Fatal(err)
+		}
+		for _, fast := range fastFrames {
+			want, _ := os.ReadFile(fast)
+			got, err := os.ReadFile(filepath.Join(dir, filepath.Base(fast)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from the fast render", filepath.Base(fast))
+			}
+		}
+	})
+
+	t.Run("parallel-pipe", func(t *testing.T) {
+		parallel := *config
+		parallel.ProcessType = ProcessTypeParallelPipe
+		parallel.Workers = 4
+		parallel.ReorderWindow = 2
+		parallel.FFmpegPath = fakeTool(t, fakeFFmpeg)
+
+		v := NewVisualizer(newVisualizerConfig(&parallel))
+		if err := v.CreateVideo(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(parallel.OutputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := renderRaw(t, config)
+		if len(got) != len(want) {
+			t.Fatalf("got %d bytes of frames, want %d", len(got), len(want))
+		}
+		frameSize := config.Width * config.Height * 4
+		for i := 0; i < len(want); i += frameSize {
+			if !bytes.Equal(got[i:i+frameSize], want[i:i+frameSize]) {
+				t.Errorf("frame %d differs from the fast render", i/frameSize)
+			}
+		}
+	})
+}
+
+// TestProbeDuration checks the duration ffprobe reports is used, and that
+// one it reports as N/A or not at all is measured by decoding instead
+func TestProbeDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   string // ffprobe output
+		decode  string // ffmpeg -progress output
+		want    float64
+		wantErr bool
+	}{
+		{"reported", "12.5\n", "", 12.5, false},
+		{"not available", "N/A\n", "out_time_us=1000000\nprogress=continue\nout_time_us=3250000\nprogress=end\n", 3.25, false},
+		{"empty", "", "out_time_us=2000000\nprogress=end\n", 2, false},
+		{"undecodable", "N/A\n", "out_time_us=N/A\nprogress=end\n", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVisualizer(&VisualizerConfig{
+				InputFile:   "clip.m4a",
+				FFprobePath: fakeTool(t, "#!/bin/sh\nprintf '"+tt.probe+"'\n"),
+				FFmpegPath:  fakeTool(t, "#!/bin/sh\nprintf '"+tt.decode+"'\n"),
+			})
+
+			got, err := v.probeDuration()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got duration %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got duration %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFrameCount checks the frame count rounds up so the video covers all
+// of the audio, ending within one frame of it
+func TestFrameCount(t *testing.T) {
+	tests := []struct {
+		duration float64
+		fps      int
+		want     int
+	}{
+		{10, 30, 300},
+		{10.01, 30, 301},
+		{10.5, 24, 252},
+		{0.51, 24, 13},
+		{0.30000000000000004, 30, 9}, // 0.1+0.2 seconds is 9.000000000000002 frames
+		{0.01, 30, 1},
+		{1.0 / 3, 60, 20},
+	}
+
+	for _, tt := range tests {
+		v := NewVisualizer(&VisualizerConfig{FPS: tt.fps})
+		got := v.frameCount(tt.duration)
+		if got != tt.want {
+			t.Errorf("frameCount(%v) at %d FPS = %d, want %d", tt.duration, tt.fps, got, tt.want)
+		}
+
+		covered := float64(got) / float64(tt.fps)
+		if covered < tt.duration-1e-6 || covered >= tt.duration+1/float64(tt.fps) {
+			t.Errorf("%d frames at %d FPS last %vs, not within a frame of %vs", got, tt.fps, covered, tt.duration)
+		}
+	}
+}
+
+// TestProbedFrameCount checks a render of a WAV file whose length isn't a
+// whole number of frames gets enough frames to reach its end
+func TestProbedFrameCount(t *testing.T) {
+	config := testConfig(t)
+	config.InputFile = writeTestWAV(t, 2.37, 44100, 440)
+
+	v := NewVisualizer(newVisualizerConfig(config))
+	if err := v.probeAudio(); err != nil {
+		t.Fatal(err)
+	}
+	if covered := float64(v.totalFrames) / float64(config.FPS); covered < v.duration || covered-v.duration >= 1/float64(config.FPS) {
+		t.Errorf("%d frames last %vs, not within a frame of the %vs audio", v.totalFrames, covered, v.duration)
+	}
+}
+
+// TestShortDuration checks a render shorter than a frame still gets one,
+// and one too short to round up to a frame fails before rendering
+func TestShortDuration(t *testing.T) {
+	tests := []struct {
+		name       string
+		seconds    float64 // Length of the WAV file
+		duration   float64 // Config.Duration
+		wantFrames int
+		wantErr    bool
+	}{
+		{"short duration", 1, 0.01, 1, false},
+		{"short file", 0.01, 0, 1, false},
+		{"zero frames", 1, 1e-8, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t)
+			config.FPS = 30
+			config.InputFile = writeTestWAV(t, tt.seconds, 44100, 440)
+			config.Duration = tt.duration
+
+			v := NewVisualizer(newVisualizerConfig(config))
+			err := v.probeAudio()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "zero frames") {
+					t.Errorf("got error %v, want a zero frames error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.totalFrames != tt.wantFrames {
+				t.Errorf("got %d frames, want %d", v.totalFrames, tt.wantFrames)
+			}
+		})
+	}
+}
+
+// TestWeightedBinRange checks fractional bin ranges weight each FFT bin by
+// how much of it they cover, and clamp to the bins there are
+func TestWeightedBinRange(t *testing.T) {
+	magnitudes := []float64{1, 2, 3, 4}
+	tests := []struct {
+		lo, hi                        float64
+		wantSum, wantPeak, wantWeight float64
+	}{
+		{0, 2, 3, 2, 2},
+		{0.5, 1.5, 1.5, 2, 1},
+		{1.25, 1.75, 1, 2, 0.5},
+		{2.2, 2.2, 3, 3, 1},    // Narrower than a bin
+		{2.5, 10, 5.5, 4, 1.5}, // Past the top
+		{10, 12, 4, 4, 1},      // Entirely past the top
+		{-1, 1, 1, 1, 1},       // Below the bottom
+	}
+
+	for _, tt := range tests {
+		sum, peak, weight := weightedBinRange(magnitudes, tt.lo, tt.hi)
+		if sum != tt.wantSum || peak != tt.wantPeak || weight != tt.wantWeight {
+			t.Errorf("weightedBinRange(%v, %v) = %v, %v, %v, want %v, %v, %v",
+				tt.lo, tt.hi, sum, peak, weight, tt.wantSum, tt.wantPeak, tt.wantWeight)
+		}
+	}
+
+	if sum, peak, weight := weightedBinRange(nil, 0, 1); sum != 0 || peak != 0 || weight != 0 {
+		t.Errorf("weightedBinRange of no bins = %v, %v, %v, want zeros", sum, peak, weight)
+	}
+}
+
+// TestFractionalBinsTone bins a pure tone in the FFT bin a bar edge cuts
+// through. Integer binning counts it in full in both bars; fractional
+// binning splits it between them by how much of the bin each covers.
+func TestFractionalBinsTone(t *testing.T) {
+	config := DefaultConfig()
+	config.BarCount = 16
+	config.FreqScale = FreqScaleLinear
+	config.AmplitudeScale = AmplitudeScaleLinear
+	config.BinAggregation = BinAggregationSum
+
+	v := NewVisualizer(newVisualizerConfig(config))
+	v.sampleRate = 22050
+	v.windowSize = 2048
+	binWidth := float64(v.sampleRate) / float64(v.windowSize)
+
+	// The edge between the first two bars, in FFT bins
+	edge := v.bandEdges()[1] / binWidth
+	tone := int(edge)
+	if edge == float64(tone) {
+		t.Fatalf("bar edge %v falls on a bin boundary", edge)
+	}
+	magnitudes := make([]float64, v.windowSize/2)
+	magnitudes[tone] = float64(v.windowSize) // A level of 1 after normalizing
+
+	tests := []struct {
+		fractional bool
+		want       [2]float64
+	}{
+		{false, [2]float64{1, 1}},
+		{true, [2]float64{edge - float64(tone), float64(tone) + 1 - edge}},
+	}
+
+	for _, tt := range tests {
+		v.config.FractionalBins = tt.fractional
+		bins := v.binFrequencies(magnitudes)
+		for i, want := range tt.want {
+			if math.Abs(bins[i]-want) > 1e-9 {
+				t.Errorf("fractional %v: bar %d = %v, want %v", tt.fractional, i, bins[i], want)
+			}
+		}
+		for i := 2; i < len(bins); i++ {
+			if bins[i] != 0 {
+				t.Errorf("fractional %v: bar %d = %v, want 0", tt.fractional, i, bins[i])
+			}
+		}
+	}
+}
+
+// TestNewVisualizerChecked checks configs that would divide by zero or give
+// bars no width are rejected, including more bars than pixels across
+func TestNewVisualizerChecked(t *testing.T) {
+	valid := func() *VisualizerConfig {
+		return &VisualizerConfig{Width: 640, Height: 360, FPS: 30, BarCount: 64, VizType: "bars"}
+	}
+	tests := []struct {
+		name    string
+		modify  func(c *VisualizerConfig)
+		wantErr bool
+	}{
+		{"valid", func(c *VisualizerConfig) {}, false},
+		{"zero width", func(c *VisualizerConfig) { c.Width = 0 }, true},
+		{"zero height", func(c *VisualizerConfig) { c.Height = 0 }, true},
+		{"zero FPS", func(c *VisualizerConfig) { c.FPS = 0 }, true},
+		{"zero bars", func(c *VisualizerConfig) { c.BarCount = 0 }, true},
+		{"one bar per pixel", func(c *VisualizerConfig) { c.BarCount = 640 }, false},
+		{"more bars than pixels", func(c *VisualizerConfig) { c.BarCount = 641 }, true},
+		{"default type", func(c *VisualizerConfig) { c.VizType, c.BarCount = "", 641 }, true},
+		{"mirror", func(c *VisualizerConfig) { c.VizType, c.BarCount = "mirror", 641 }, true},
+		{"butterfly", func(c *VisualizerConfig) { c.VizType, c.BarCount = "butterfly", 641 }, true},
+		{"circular", func(c *VisualizerConfig) { c.VizType, c.BarCount = "circular", 641 }, false},
+	}
+
+	for _, tt := range tests {
+		config := valid()
+		tt.modify(config)
+		v, err := NewVisualizerChecked(config)
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("%s: got no error", tt.name)
+		case !tt.wantErr && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !tt.wantErr && usesBarSlots(config.VizType) && v.barWidth <= 0:
+			t.Errorf("%s: bars are %v pixels wide", tt.name, v.barWidth)
+		}
+	}
+
+	if _, err := NewVisualizerChecked(nil); err == nil {
+		t.Error("nil config: got no error")
+	}
+}
+
+// TestFormatETA checks ETAs print as mm:ss, with hours from an hour up
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "00:00"},
+		{42 * time.Second, "00:42"},
+		{1500 * time.Millisecond, "00:02"},
+		{59*time.Minute + 59*time.Second, "59:59"},
+		{time.Hour, "1:00:00"},
+		{26*time.Hour + 3*time.Minute + 4*time.Second, "26:03:04"},
+	}
+
+	for _, tt := range tests {
+		if got := formatETA(tt.d); got != tt.want {
+			t.Errorf("formatETA(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// TestFrameProgress checks the ETA appears once a rate has been sampled
+// and follows a moving average of the rate after that
+func TestFrameProgress(t *testing.T) {
+	p := newFrameProgress(100)
+	if got, want := p.line("Processing", 0), "Processing frame 0/100 (0.0%)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// 50 frames in 10 seconds leaves 50 frames at 5 per second
+	p.last = time.Now().Add(-10 * time.Second)
+	if got, want := p.line("Processing", 50), "Processing frame 50/100 (50.0%, ETA 00:10)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Slowing to 1 frame per second averages to 3.8, leaving 40 frames
+	p.last = time.Now().Add(-10 * time.Second)
+	if got, want := p.line("Processed", 60), "Processed frame 60/100 (60.0%, ETA 00:11)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// No new frames leaves the rate and ETA as they were
+	if got, want := p.line("Processed", 60), "Processed frame 60/100 (60.0%, ETA 00:11)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestOutputArgsLoop checks LoopCount maps to the -loop value each animated
+// format's muxer expects
+func TestOutputArgsLoop(t *testing.T) {
+	tests := []struct {
+		output    string
+		loopCount int
+		want      string
+	}{
+		{"out.gif", 0, "0"},  // Loop forever
+		{"out.gif", 1, "-1"}, // Play once
+		{"out.gif", 3, "2"},  // Play three times
+		{"out.webp", 0, "0"},
+		{"out.webp", 1, "1"},
+		{"out.webp", 3, "3"},
+	}
+
+	for _, tt := range tests {
+		v := NewVisualizer(&VisualizerConfig{OutputFile: tt.output, LoopCount: tt.loopCount})
+		args := v.outputArgs()
+
+		loop := -1
+		for i, arg := range args[:len(args)-1] {
+			if arg == "-loop" {
+				loop = i + 1
+			}
+		}
+		if loop < 0 {
+			t.Errorf("%s with LoopCount %d: no -loop in %q", tt.output, tt.loopCount, args)
+		} else if args[loop] != tt.want {
+			t.Errorf("%s with LoopCount %d: got -loop %s, want %s", tt.output, tt.loopCount, args[loop], tt.want)
+		}
+	}
+}
+
+// TestOutputArgsQuality checks VideoCRF reaches each encoder as its own
+// quality flag
+func TestOutputArgsQuality(t *testing.T) {
+	tests := []struct {
+		encoder string
+		crf     int
+		flag    string
+		want    string
+	}{
+		{"libx264", 23, "-crf", "23"},
+		{"h264_nvenc", 23, "-cq", "23"},
+		{"h264_qsv", 23, "-global_quality", "23"},
+		{"h264_videotoolbox", 23, "-q:v", "54"},
+		{"h264_videotoolbox", 1, "-q:v", "98"},
+		{"h264_videotoolbox", 51, "-q:v", "1"},
+		{"h264_videotoolbox", 0, "-q:v", ""}, // Encoder default
+		{"libvpx-vp9", 30, "-crf", "30"},
+	}
+
+	for _, tt := range tests {
+		v := NewVisualizer(&VisualizerConfig{OutputFile: "out.mp4", VideoCRF: tt.crf})
+		v.videoEncoder = tt.encoder
+		args := v.outputArgs()
+
+		var got string
+		for i, arg := range args[:len(args)-1] {
+			if arg == tt.flag {
+				got = args[i+1]
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s with CRF %d: got %s %q, want %q", tt.encoder, tt.crf, tt.flag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package audiospectrum
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WAV format codes for integer PCM, IEEE float and the extensible header,
+// whose subformat holds one of the other two
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xfffe
+)
+
+// wavInfo describes the sample layout and data chunk of a WAV file
+type wavInfo struct {
+	format        uint16
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	dataOffset    int64
+	dataSize      int64
+}
+
+// duration returns the length of the data chunk in seconds
+func (w *wavInfo) duration() float64 {
+	return float64(w.dataSize/int64(w.blockAlign())) / float64(w.sampleRate)
+}
+
+// blockAlign returns the size in bytes of one frame of all channels
+func (w *wavInfo) blockAlign() int {
+	return w.channels * w.bitsPerSample / 8
+}
+
+// readWAVHeader parses the RIFF chunks of a WAV file up to its data chunk,
+// returning an error for anything but mono or stereo integer PCM (8, 16, 24
+// or 32 bit) or float (32 or 64 bit) samples
+func readWAVHeader(path string) (*wavInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	
+	var riff [12]byte
+	if _, err := io.ReadFull(f, riff[:]); err != nil {
+		return nil, fmt.Errorf("reading RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a RIFF WAVE file")
+	}
+	
+	info := &wavInfo{}
+	offset := int64(12)
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			return nil, fmt.Errorf("no data chunk found")
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		offset += 8
+		
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("fmt chunk too short")
+			}
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(f, chunk); err != nil {
+				return nil, fmt.Errorf("reading fmt chunk: %w", err)
+			}
+			info.format = binary.LittleEndian.Uint16(chunk[0:2])
+			info.channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			info.sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			info.bitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			if info.format == wavFormatExtensible && size >= 26 {
+				// The subformat GUID starts with the actual format code
+				info.format = binary.LittleEndian.Uint16(chunk[24:26])
+			}
+		case "data":
+			if info.sampleRate == 0 {
+				return nil, fmt.Errorf("data chunk before fmt chunk")
+			}
+			info.dataOffset = offset
+			
+			// Streamed WAVs leave the size unset, so take the rest of the file
+			stat, err := f.Stat()
+			if err != nil {
+				return nil, err
+			}
+			if size == 0 || size == math.MaxUint32 || offset+size > stat.Size() {
+				size = stat.Size() - offset
+			}
+			info.dataSize = size
+			return info, info.check()
+		}
+		
+		// Chunks are padded to an even length
+		skip := size + size%2
+		if _, err := f.Seek(offset+skip, io.SeekStart); err != nil {
+			return nil, err
+		}
+		offset += skip
+	}
+}
+
+// check reports whether the sample layout is one decodeWAV handles
+func (w *wavInfo) check() error {
+	switch {
+	case w.channels < 1 || w.channels > 2:
+		return fmt.Errorf("%d channels", w.channels)
+	case w.sampleRate <= 0:
+		return fmt.Errorf("invalid sample rate %d", w.sampleRate)
+	case w.format == wavFormatPCM && (w.bitsPerSample == 8 || w.bitsPerSample == 16 || w.bitsPerSample == 24 || w.bitsPerSample == 32):
+		return nil
+	case w.format == wavFormatFloat && (w.bitsPerSample == 32 || w.bitsPerSample == 64):
+		return nil
+	}
+	return fmt.Errorf("unsupported format %d with %d bits per sample", w.format, w.bitsPerSample)
+}
+
+// nativeWAV returns the header of the input when it is a WAV file that can
+// be decoded without ffmpeg, or nil to use ffmpeg. Segments and streamed
+// input always go through ffmpeg.
+func (v *Visualizer) nativeWAV() *wavInfo {
+	if v.inputReader != nil || len(v.config.Segments) > 0 {
+		return nil
+	}
+	if strings.ToLower(filepath.Ext(v.config.InputFile)) != ".wav" {
+		return nil
+	}
+	
+	info, err := readWAVHeader(v.config.InputFile)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// decodeWAV reads the analysed part of a WAV file directly into audioData:
+// sourceLength seconds from StartTime, averaged to mono and resampled to
+// the analysis rate
+func (v *Visualizer) decodeWAV(info *wavInfo) error {
+	f, err := os.Open(v.config.InputFile)
+	if err != nil {
+		return fmt.Errorf("opening audio: %w", err)
+	}
+	defer f.Close()
+	
+	block := int64(info.blockAlign())
+	total := info.dataSize / block
+	start := min(int64(v.config.StartTime*float64(info.sampleRate)), total)
+	frames := total - start
+	if v.sourceLength > 0 {
+		frames = min(frames, int64(math.Round(v.sourceLength*float64(info.sampleRate))))
+	}
+	
+	data := make([]byte, frames*block)
+	if _, err := f.ReadAt(data, info.dataOffset+start*block); err != nil && err != io.EOF {
+		return fmt.Errorf("reading audio data: %w", err)
+	}
+	
+	bytesPerSample := info.bitsPerSample / 8
+	mono := make([]float64, frames)
+	for i := range mono {
+		var sum float64
+		for ch := 0; ch < info.channels; ch++ {
+			sum += wavSample(data[int64(i)*block+int64(ch*bytesPerSample):], info.format, info.bitsPerSample)
+		}
+		mono[i] = sum / float64(info.channels)
+	}
+	
+	v.audioData = resample(mono, info.sampleRate, v.sampleRate)
+	return nil
+}
+
+// wavSample converts the little-endian sample at the start of b to -1..1
+func wavSample(b []byte, format uint16, bits int) float64 {
+	if format == wavFormatFloat {
+		if bits == 64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	
+	switch bits {
+	case 8:
+		// 8-bit WAV is the only unsigned PCM
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 24:
+		return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+	}
+}
+
+// Resampling filter: a Blackman-windowed sinc reaching resampleZeros zero
+// crossings each side, with its passband ending at resampleCutoff of the
+// lower Nyquist frequency. Bars stop at about 0.73 of Nyquist, so the
+// transition band above the cutoff folds nothing back into them.
+const (
+	resampleZeros  = 16
+	resampleCutoff = 0.85
+	resampleTable  = 512 // Kernel lookup entries per zero crossing
+)
+
+// resample converts samples from one rate to another like ffmpeg's
+// resampler, low-pass filtering with a windowed sinc so downsampling doesn't
+// alias high frequencies into the top bands
+func resample(samples []float64, from, to int) []float64 {
+	if from == to || len(samples) == 0 {
+		return samples
+	}
+	
+	kernel := resampleKernel()
+	ratio := float64(from) / float64(to)
+	scale := math.Max(ratio, 1) // Source samples per zero crossing
+	reach := resampleZeros * scale
+	
+	out := make([]float64, len(samples)*to/from)
+	for i := range out {
+		center := float64(i) * ratio
+		lo := max(int(math.Ceil(center-reach)), 0)
+		hi := min(int(center+reach), len(samples)-1)
+		
+		// Dividing by the summed weights keeps unity gain, including at the
+		// edges where the filter runs off the samples
+		var sum, weight float64
+		for k := lo; k <= hi; k++ {
+			pos := math.Abs(float64(k)-center) / scale * resampleTable
+			j := int(pos)
+			if j >= len(kernel)-1 {
+				continue
+			}
+			h := kernel[j] + (kernel[j+1]-kernel[j])*(pos-float64(j))
+			sum += samples[k] * h
+			weight += h
+		}
+		if weight != 0 {
+			out[i] = sum / weight
+		}
+	}
+	return out
+}
+
+// resampleKernel tabulates one side of the resampling filter, resampleTable
+// entries per zero crossing
+func resampleKernel() []float64 {
+	kernel := make([]float64, resampleZeros*resampleTable+1)
+	for j := range kernel {
+		x := float64(j) / resampleTable
+		window := 0.42 + 0.5*math.Cos(math.Pi*x/resampleZeros) + 0.08*math.Cos(2*math.Pi*x/resampleZeros)
+		sinc := 1.0
+		if x > 0 {
+			sinc = math.Sin(math.Pi*resampleCutoff*x) / (math.Pi * resampleCutoff * x)
+		}
+		kernel[j] = sinc * window
+	}
+	return kernel
+}
@@ -0,0 +1,238 @@
+package audiospectrum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// wavFile builds a WAV file with a fmt chunk for the given layout, any extra
+// chunks (id followed by contents) before the data chunk, and data
+func wavFile(format uint16, channels, rate, bits int, data []byte, extra ...string) []byte {
+	var fmtChunk bytes.Buffer
+	for _, field := range []any{
+		format, uint16(channels), uint32(rate),
+		uint32(rate * channels * bits / 8), uint16(channels * bits / 8), uint16(bits),
+	} {
+		binary.Write(&fmtChunk, binary.LittleEndian, field)
+	}
+
+	var chunks bytes.Buffer
+	writeChunk := func(id string, contents []byte) {
+		chunks.WriteString(id)
+		binary.Write(&chunks, binary.LittleEndian, uint32(len(contents)))
+		chunks.Write(contents)
+		if len(contents)%2 == 1 {
+			chunks.WriteByte(0)
+		}
+	}
+	writeChunk("fmt ", fmtChunk.Bytes())
+	for _, chunk := range extra {
+		writeChunk(chunk[:4], []byte(chunk[4:]))
+	}
+	writeChunk("data", data)
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(4+chunks.Len()))
+	file.WriteString("WAVE")
+	file.Write(chunks.Bytes())
+	return file.Bytes()
+}
+
+// saveWAV writes a WAV file built by wavFile to a temp file
+func saveWAV(t *testing.T, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestReadWAVHeader checks the sample layout and data chunk are found past
+// other chunks, and unsupported files are refused
+func TestReadWAVHeader(t *testing.T) {
+	data := make([]byte, 4*44100) // One second of 16-bit stereo
+	tests := []struct {
+		name     string
+		file     []byte
+		want     wavInfo
+		duration float64
+		wantErr  bool
+	}{
+		{
+			name:     "16-bit stereo",
+			file:     wavFile(wavFormatPCM, 2, 44100, 16, data),
+			want:     wavInfo{format: wavFormatPCM, channels: 2, sampleRate: 44100, bitsPerSample: 16, dataOffset: 44, dataSize: int64(len(data))},
+			duration: 1,
+		},
+		{
+			name:     "odd chunk before data",
+			file:     wavFile(wavFormatPCM, 2, 44100, 16, data, "LISTodd"),
+			want:     wavInfo{format: wavFormatPCM, channels: 2, sampleRate: 44100, bitsPerSample: 16, dataOffset: 56, dataSize: int64(len(data))},
+			duration: 1,
+		},
+		{
+			name:     "32-bit float mono",
+			file:     wavFile(wavFormatFloat, 1, 22050, 32, data),
+			want:     wavInfo{format: wavFormatFloat, channels: 1, sampleRate: 22050, bitsPerSample: 32, dataOffset: 44, dataSize: int64(len(data))},
+			duration: 2,
+		},
+		{name: "surround", file: wavFile(wavFormatPCM, 6, 44100, 16, data), wantErr: true},
+		{name: "12-bit", file: wavFile(wavFormatPCM, 2, 44100, 12, data), wantErr: true},
+		{name: "compressed", file: wavFile(2, 2, 44100, 4, data), wantErr: true},
+		{name: "not a WAV", file: []byte("ID3\x04\x00\x00\x00\x00\x00\x00 not audio"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		info, err := readWAVHeader(saveWAV(t, tt.file))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %+v, want an error", tt.name, *info)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if *info != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *info, tt.want)
+		}
+		if got := info.duration(); got != tt.duration {
+			t.Errorf("%s: got duration %v, want %v", tt.name, got, tt.duration)
+		}
+	}
+}
+
+// TestWAVSample checks each sample format converts to -1..1
+func TestWAVSample(t *testing.T) {
+	float32Bytes := func(f float32) []byte {
+		return binary.LittleEndian.AppendUint32(nil, math.Float32bits(f))
+	}
+	float64Bytes := func(f float64) []byte {
+		return binary.LittleEndian.AppendUint64(nil, math.Float64bits(f))
+	}
+
+	tests := []struct {
+		format uint16
+		bits   int
+		b      []byte
+		want   float64
+	}{
+		{wavFormatPCM, 8, []byte{0}, -1},
+		{wavFormatPCM, 8, []byte{128}, 0},
+		{wavFormatPCM, 8, []byte{192}, 0.5},
+		{wavFormatPCM, 16, []byte{0x00, 0x80}, -1},
+		{wavFormatPCM, 16, []byte{0x00, 0x40}, 0.5},
+		{wavFormatPCM, 16, []byte{0xff, 0xff}, -1.0 / 32768},
+		{wavFormatPCM, 24, []byte{0x00, 0x00, 0x80}, -1},
+		{wavFormatPCM, 24, []byte{0x00, 0x00, 0x40}, 0.5},
+		{wavFormatPCM, 24, []byte{0x00, 0x00, 0xe0}, -0.25},
+		{wavFormatPCM, 32, []byte{0x00, 0x00, 0x00, 0x80}, -1},
+		{wavFormatPCM, 32, []byte{0x00, 0x00, 0x00, 0x40}, 0.5},
+		{wavFormatFloat, 32, float32Bytes(0.25), 0.25},
+		{wavFormatFloat, 64, float64Bytes(-0.75), -0.75},
+	}
+
+	for _, tt := range tests {
+		if got := wavSample(tt.b, tt.format, tt.bits); got != tt.want {
+			t.Errorf("wavSample(% x, %d, %d) = %v, want %v", tt.b, tt.format, tt.bits, got, tt.want)
+		}
+	}
+}
+
+// TestDecodeWAV checks stereo is averaged to mono and only the analysed
+// part from StartTime is read
+func TestDecodeWAV(t *testing.T) {
+	// Ten frames of 16-bit stereo, left at i/16 and right at -i/32
+	var data []byte
+	for i := 0; i < 10; i++ {
+		data = binary.LittleEndian.AppendUint16(data, uint16(int16(i*2048)))
+		data = binary.LittleEndian.AppendUint16(data, uint16(int16(-i*1024)))
+	}
+	path := saveWAV(t, wavFile(wavFormatPCM, 2, 10, 16, data))
+
+	tests := []struct {
+		name         string
+		startTime    float64
+		sourceLength float64
+		want         []float64
+	}{
+		{"whole file", 0, 0, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"from start time", 0.4, 0, []float64{4, 5, 6, 7, 8, 9}},
+		{"capped length", 0.2, 0.5, []float64{2, 3, 4, 5, 6}},
+		{"past the end", 2, 0, []float64{}},
+	}
+
+	for _, tt := range tests {
+		v := NewVisualizer(&VisualizerConfig{InputFile: path, StartTime: tt.startTime})
+		v.sampleRate = 10 // Analyse at the file's rate, so nothing is resampled
+		v.sourceLength = tt.sourceLength
+
+		info := v.nativeWAV()
+		if info == nil {
+			t.Fatal("WAV file not read natively")
+		}
+		if err := v.decodeWAV(info); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(v.audioData) != len(tt.want) {
+			t.Errorf("%s: got %d samples, want %d", tt.name, len(v.audioData), len(tt.want))
+			continue
+		}
+		for i, frame := range tt.want {
+			// The mean of i/16 and -i/32
+			if want := frame / 64; v.audioData[i] != want {
+				t.Errorf("%s: sample %d = %v, want %v", tt.name, i, v.audioData[i], want)
+			}
+		}
+	}
+}
+
+// TestResample checks downsampling keeps tones below the new Nyquist
+// frequency and filters out those above it instead of aliasing them
+func TestResample(t *testing.T) {
+	tests := []struct {
+		freq     float64
+		from, to int
+		wantGain float64 // Amplitude after resampling, within 0.01
+	}{
+		{1000, 44100, 22050, 1},
+		{6000, 48000, 22050, 1},
+		{1000, 22050, 44100, 1},
+		{20000, 44100, 22050, 0},
+		{15000, 48000, 22050, 0},
+	}
+
+	for _, tt := range tests {
+		samples := make([]float64, tt.from)
+		for i := range samples {
+			samples[i] = math.Sin(2 * math.Pi * tt.freq * float64(i) / float64(tt.from))
+		}
+
+		out := resample(samples, tt.from, tt.to)
+		if len(out) != tt.to {
+			t.Errorf("%v Hz from %d to %d: got %d samples, want %d", tt.freq, tt.from, tt.to, len(out), tt.to)
+			continue
+		}
+
+		// Measure away from the edges, where the filter runs off the samples
+		var peak float64
+		for _, s := range out[tt.to/4 : tt.to*3/4] {
+			peak = math.Max(peak, math.Abs(s))
+		}
+		if math.Abs(peak-tt.wantGain) > 0.01 {
+			t.Errorf("%v Hz from %d to %d: got amplitude %.4f, want %v", tt.freq, tt.from, tt.to, peak, tt.wantGain)
+		}
+	}
+
+	if got := resample([]float64{1, 2, 3}, 44100, 44100); len(got) != 3 || got[2] != 3 {
+		t.Errorf("resampling to the same rate changed the samples: %v", got)
+	}
+}